@@ -0,0 +1,138 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/ghdwlsgur/gostat/internal"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// checkSpecs returns the checks of the profile when no url is given, otherwise a check
+// per url built from the flags. The thresholds of the profile fill in what is not asserted.
+func checkSpecs(cmd *cobra.Command, args []string) ([]internal.CheckSpec, error) {
+	var specs []internal.CheckSpec
+
+	if len(args) == 0 && viper.IsSet("checks") {
+		if err := viper.UnmarshalKey("checks", &specs); err != nil {
+			return nil, err
+		}
+	} else {
+		urls, err := edgeURLs(cmd, args)
+		if err != nil {
+			return nil, err
+		}
+
+		latency := map[string]time.Duration{}
+		for phase, value := range viper.GetStringMapString("check-latency") {
+			d, err := time.ParseDuration(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid latency of %s: %w", phase, err)
+			}
+			latency[phase] = d
+		}
+
+		headers, forbidden := map[string]string{}, map[string]string{}
+		for _, value := range viper.GetStringSlice("check-require-headers") {
+			field, pattern := internal.ParseHeaderAssertion(value)
+			headers[field] = pattern
+		}
+		for _, value := range viper.GetStringSlice("check-forbid-headers") {
+			field, pattern := internal.ParseHeaderAssertion(value)
+			forbidden[field] = pattern
+		}
+
+		for _, url := range urls {
+			specs = append(specs, internal.CheckSpec{
+				URL:              url,
+				Status:           viper.GetInt("check-status"),
+				Headers:          headers,
+				ForbiddenHeaders: forbidden,
+				Latency:          latency,
+				SameHash:         viper.GetBool("check-same-hash"),
+				CertDays:         viper.GetInt("check-cert-days"),
+			})
+		}
+	}
+
+	for i := range specs {
+		if len(specs[i].Latency) == 0 && viper.IsSet("thresholds.latency") {
+			specs[i].Latency = map[string]time.Duration{"total": viper.GetDuration("thresholds.latency")}
+		}
+		if specs[i].CertDays == 0 {
+			specs[i].CertDays = viper.GetInt("thresholds.cert-days")
+		}
+		if err := specs[i].Validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	return specs, nil
+}
+
+var (
+	checkCommand = &cobra.Command{
+		Use:   "check",
+		Short: "Exec `gostat check https://domain.com --status 200 --same-hash`",
+		Long:  "Asserts the status, headers, latency, body hash and certificate of the URL on each A record of the target domain, and exits with status 1 and a failure report when any assertion fails. With --output junit or tap the results are written as a JUnit XML or TAP report.",
+		PreRun: func(cmd *cobra.Command, args []string) {
+			bindRequestFlags(cmd)
+			viper.BindPFlag("check-status", cmd.Flags().Lookup("status"))
+			viper.BindPFlag("check-require-headers", cmd.Flags().Lookup("require-header"))
+			viper.BindPFlag("check-forbid-headers", cmd.Flags().Lookup("forbid-header"))
+			viper.BindPFlag("check-latency", cmd.Flags().Lookup("max-latency"))
+			viper.BindPFlag("check-same-hash", cmd.Flags().Lookup("same-hash"))
+			viper.BindPFlag("check-cert-days", cmd.Flags().Lookup("cert-days"))
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			ctx := cmd.Context()
+			specs, err := checkSpecs(cmd, args)
+			if err != nil {
+				panicRed(err)
+			}
+
+			var results []internal.CheckResult
+			for _, spec := range specs {
+				edge, err := parseEdgeURL(ctx, spec.URL)
+				if err != nil {
+					results = append(results, internal.ResolveFailure(spec, err))
+					continue
+				}
+				edge.requestOptions.FullBody = true
+
+				responses := probeEdges(ctx, edge.ips, edge.addrInfo, edge.requestOptions, edge.protocol)
+				results = append(results, internal.RunChecks(spec, responses)...)
+			}
+
+			switch viper.GetString("output-format") {
+			case "junit":
+				err = internal.WriteJUnit(os.Stdout, results)
+			case "tap":
+				err = internal.WriteTAP(os.Stdout, results)
+			default:
+				internal.PrintCheckResults(results)
+			}
+			if err != nil {
+				panicRed(err)
+			}
+
+			if !internal.ChecksPassed(results) {
+				os.Exit(1)
+			}
+		},
+	}
+)
+
+func init() {
+	addRequestFlags(checkCommand)
+	checkCommand.Flags().Int("status", 0, "[optional] Expected status code of every edge.")
+	checkCommand.Flags().StringArray("require-header", nil, "[optional] Header that must be present, as `Name: regex`.")
+	checkCommand.Flags().StringArray("forbid-header", nil, "[optional] Header that must be absent, or not match when given as `Name: regex`.")
+	checkCommand.Flags().StringToString("max-latency", nil, "[optional] Maximum latency per step (dns_lookup, tcp_connection, tls_handshake, server_processing, total), e.g. total=500ms.")
+	checkCommand.Flags().Bool("same-hash", false, "[optional] Every edge must serve the same body hash.")
+	checkCommand.Flags().Int("cert-days", 0, "[optional] Minimum number of days the certificate must still be valid.")
+
+	rootCmd.AddCommand(checkCommand)
+}
@@ -0,0 +1,96 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/ghdwlsgur/gostat/internal"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// resolveOrigin returns the origin as an ip, resolving the first A record when a host is given.
+func resolveOrigin(ctx context.Context, origin string) (string, error) {
+	if net.ParseIP(origin) != nil {
+		return origin, nil
+	}
+
+	ips, err := internal.GetRecordIPv4(ctx, origin)
+	if err != nil {
+		return "", err
+	}
+	if len(ips) == 0 {
+		return "", fmt.Errorf("no A record found for the origin %s", origin)
+	}
+	return ips[0], nil
+}
+
+// fetchOrigin requests the url straight from the origin with the same options as the edges.
+func fetchOrigin(ctx context.Context, edge *edgeArgs, origin string) (*internal.Response, error) {
+	ip, err := resolveOrigin(ctx, origin)
+	if err != nil {
+		return nil, err
+	}
+
+	addr := *edge.addrInfo
+	addr.Target = origin
+	addr.IP = ip
+
+	response := fetchEdge(ctx, &addr, edge.requestOptions, edge.protocol)
+	return response, response.Error
+}
+
+var (
+	compareCommand = &cobra.Command{
+		Use:   "compare",
+		Short: "Exec `gostat compare https://domain.com -t domain.com`",
+		Long:  "Requests the full body of the URL from each A record of the target domain and reports the edges serving stale or divergent content by comparing hash, ETag, Last-Modified, Content-Length and Cache-Control. With an origin, each edge is diffed against the response of the origin instead.",
+		PreRun: func(cmd *cobra.Command, args []string) {
+			bindRequestFlags(cmd)
+			viper.BindPFlag("origin-address", cmd.Flags().Lookup("origin"))
+			viper.BindPFlag("max-body-size", cmd.Flags().Lookup("max-body-size"))
+			viper.BindPFlag("ignore-patterns", cmd.Flags().Lookup("ignore"))
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			ctx := cmd.Context()
+			edge, err := parseEdgeArgs(cmd, args)
+			if err != nil {
+				panicRed(err)
+			}
+			edge.requestOptions.FullBody = true
+			edge.requestOptions.BodyLimit = viper.GetInt64("max-body-size")
+
+			ignore, err := internal.CompileIgnorePatterns(viper.GetStringSlice("ignore-patterns"))
+			if err != nil {
+				panicRed(err)
+			}
+
+			origin := strings.TrimSpace(viper.GetString("origin-address"))
+			if origin == "" {
+				responses := probeEdges(ctx, edge.ips, edge.addrInfo, edge.requestOptions, edge.protocol)
+				internal.PrintComparison(edge.rawURL, internal.CompareResponses(responses))
+				internal.PrintBodyDiffs(internal.DiffBodies(responses, ignore))
+				return
+			}
+
+			originResponse, err := fetchOrigin(ctx, edge, origin)
+			if err != nil {
+				panicRed(err)
+			}
+
+			responses := probeEdges(ctx, edge.ips, edge.addrInfo, edge.requestOptions, edge.protocol)
+			internal.PrintOriginDiff(edge.rawURL, originResponse, internal.DiffAgainstOrigin(originResponse, responses))
+		},
+	}
+)
+
+func init() {
+	addRequestFlags(compareCommand)
+	compareCommand.Flags().StringP("origin", "o", "", "[optional] The origin (ip or host) to diff each edge against.")
+	compareCommand.Flags().Int64("max-body-size", 1<<20, "[optional] Bytes of each body kept to diff the bodies of divergent edges.")
+	compareCommand.Flags().StringSlice("ignore", nil, "[optional] Regular expressions ignored when diffing text bodies, such as timestamps or nonces.")
+
+	rootCmd.AddCommand(compareCommand)
+}
@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"net/http"
+
+	"github.com/ghdwlsgur/gostat/internal"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var (
+	compressionCommand = &cobra.Command{
+		Use:   "compression",
+		Short: "Exec `gostat compression https://domain.com -t domain.com`",
+		Long:  "Requests the URL from each A record of the target domain with identity, gzip, br and zstd explicitly and without transparent decompression, and reports Content-Encoding, compressed size, ratio and Vary per edge.",
+		PreRun: func(cmd *cobra.Command, args []string) {
+			bindRequestFlags(cmd)
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			ctx := cmd.Context()
+			edge, err := parseEdgeArgs(cmd, args)
+			if err != nil {
+				panicRed(err)
+			}
+			edge.requestOptions.FullBody = true
+			edge.requestOptions.RawEncoding = true
+
+			ips := internal.SortIPs(edge.ips)
+			reports := make([]internal.CompressionReport, len(ips))
+			forEachEdge(ips, edge.addrInfo, viper.GetInt("concurrency-count"), func(i int, addr *internal.Address) {
+				reports[i].EdgeIP = addr.IP
+				for _, encoding := range internal.Encodings {
+					requestOptions := edge.requestOptions.Clone()
+					if requestOptions.Header == nil {
+						requestOptions.Header = http.Header{}
+					}
+					requestOptions.Header.Set("Accept-Encoding", encoding)
+
+					response := fetchEdge(ctx, addr, requestOptions, edge.protocol)
+					reports[i].Results = append(reports[i].Results, internal.NewEncodingResult(encoding, response))
+				}
+			})
+
+			internal.PrintCompressionReports(edge.rawURL, reports)
+		},
+	}
+)
+
+func init() {
+	addRequestFlags(compressionCommand)
+
+	rootCmd.AddCommand(compressionCommand)
+}
@@ -0,0 +1,193 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/ghdwlsgur/gostat/internal"
+	"github.com/ghdwlsgur/gostat/pkg/gostat"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// edgeArgs holds what every edge probing command needs after parsing its arguments.
+type edgeArgs struct {
+	rawURL         string
+	protocol       string
+	ips            []string
+	addrInfo       *internal.Address
+	requestOptions *internal.ReqOptions
+}
+
+// addRequestFlags registers the flags shared by the commands that request each edge.
+func addRequestFlags(cmd *cobra.Command) {
+	cmd.Flags().StringP("target", "t", "", "[required] Receive responses by proxying the A record of the domain forwarded to the target.")
+	cmd.Flags().IntP("port", "p", 80, "[optional] For http protocol, the default value is 80.")
+	cmd.Flags().StringP("host", "H", "", "[optional] The host to put in the request headers.")
+	cmd.Flags().StringP("authorization", "A", "", "[optional]")
+	cmd.Flags().StringP("referer", "r", "", "[optional]")
+	cmd.Flags().IntP("concurrency", "c", 4, "[optional] number of edges requested at the same time")
+}
+
+// bindRequestFlags binds the shared flags of the running command.
+// It is called from PreRun because every command registers flags under the same keys.
+func bindRequestFlags(cmd *cobra.Command) {
+	viper.BindPFlag("target-domain", cmd.Flags().Lookup("target"))
+	viper.BindPFlag("port-number", cmd.Flags().Lookup("port"))
+	viper.BindPFlag("host-name", cmd.Flags().Lookup("host"))
+	viper.BindPFlag("authorization-name", cmd.Flags().Lookup("authorization"))
+	viper.BindPFlag("referer-name", cmd.Flags().Lookup("referer"))
+	viper.BindPFlag("concurrency-count", cmd.Flags().Lookup("concurrency"))
+}
+
+// edgeURLs returns the url arguments, or the url list of the profile when none is given.
+func edgeURLs(cmd *cobra.Command, args []string) ([]string, error) {
+	if len(args) == 0 {
+		args = viper.GetStringSlice("urls")
+	}
+	if err := cobra.MinimumNArgs(1)(cmd, args); err != nil {
+		return nil, err
+	}
+	return args, nil
+}
+
+// parseEdgeArgs checks the url argument and resolves the A records of the target.
+func parseEdgeArgs(cmd *cobra.Command, args []string) (*edgeArgs, error) {
+	urls, err := edgeURLs(cmd, args)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(urls) > 1 {
+		return nil, fmt.Errorf("up to one argument can be entered")
+	}
+	return parseEdgeURL(cmd.Context(), urls[0])
+}
+
+// parseEdgeURL checks the url format and resolves the A records of the target.
+func parseEdgeURL(ctx context.Context, rawURL string) (*edgeArgs, error) {
+	return parseEdgeTarget(ctx, rawURL, viper.GetString("target-domain"))
+}
+
+// parseEdgeTarget is parseEdgeURL with the target given instead of read from the flags,
+// an empty target resolves the domain of the url.
+func parseEdgeTarget(ctx context.Context, rawURL, target string) (*edgeArgs, error) {
+	splitData := strings.Split(rawURL, "://")
+
+	// Check the url format.
+	protocol, err := getProtocol(splitData)
+	if err != nil {
+		return nil, err
+	}
+	if len(splitData) < 2 || (protocol != "http" && protocol != "https") {
+		return nil, fmt.Errorf("the input format is incorrect")
+	}
+
+	url := splitData[1]
+	domainName := strings.Split(url, "/")[0]
+	target = strings.TrimSpace(target)
+	if target == "" {
+		target = domainName
+	}
+
+	ips, err := internal.GetRecordIPv4On(ctx, strings.TrimSpace(viper.GetString("resolver-address")), target)
+	if err != nil {
+		return nil, &gostat.ProbeError{Kind: gostat.Classify(err), Err: err}
+	}
+
+	retryOn, err := internal.ParseRetryOn(viper.GetStringSlice("retry-on"))
+	if err != nil {
+		return nil, err
+	}
+
+	header := http.Header{}
+	for field, value := range viper.GetStringMapString("request-headers") {
+		header.Set(field, value)
+	}
+
+	return &edgeArgs{
+		rawURL:   rawURL,
+		protocol: protocol,
+		ips:      ips,
+		// ! [required] Enter your address information.
+		addrInfo: &internal.Address{
+			Url:        url,
+			DomainName: domainName,
+			Target:     target,
+		},
+		// [optional] It is additionally saved when entering a header or referrer.
+		requestOptions: &internal.ReqOptions{
+			Host:           strings.TrimSpace(viper.GetString("host-name")),
+			Referer:        strings.TrimSpace(viper.GetString("referer-name")),
+			Authorization:  strings.TrimSpace(viper.GetString("authorization-name")),
+			Port:           viper.GetInt("port-number"),
+			Header:         header,
+			ConnectTimeout: viper.GetDuration("connect-timeout"),
+			Timeout:        viper.GetDuration("request-timeout"),
+			Retry: internal.RetryPolicy{
+				Retries: viper.GetInt("retries"),
+				Backoff: viper.GetDuration("retry-backoff"),
+				On:      retryOn,
+			},
+		},
+	}, nil
+}
+
+// fetchEdge requests the url to a single edge with the protocol of the url.
+func fetchEdge(ctx context.Context, addr *internal.Address, requestOptions *internal.ReqOptions, protocol string) *internal.Response {
+	if protocol == "http" {
+		return internal.FetchHTTP(ctx, addr, requestOptions)
+	}
+	return internal.FetchHTTPS(ctx, addr, requestOptions)
+}
+
+// forEachEdge calls fn for every edge in ascending IP order with at most concurrency
+// calls in flight, each call receives its own copy of the address pinned to the edge.
+func forEachEdge(ips []string, addrInfo *internal.Address, concurrency int, fn func(i int, addr *internal.Address)) {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	if concurrency > len(ips) {
+		concurrency = len(ips)
+	}
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				addr := *addrInfo
+				addr.IP = ips[i]
+				fn(i, &addr)
+			}
+		}()
+	}
+
+	for i := range ips {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+}
+
+// probeEdges requests every edge concurrently and returns the responses
+// in ascending IP order regardless of arrival order, recording them with --record.
+func probeEdges(ctx context.Context, ips []string, addrInfo *internal.Address, requestOptions *internal.ReqOptions, protocol string) []*internal.Response {
+	ips = internal.SortIPs(ips)
+	responses := make([]*internal.Response, len(ips))
+
+	forEachEdge(ips, addrInfo, viper.GetInt("concurrency-count"), func(i int, addr *internal.Address) {
+		responses[i] = fetchEdge(ctx, addr, requestOptions, protocol)
+	})
+
+	// The edges canceled before answering are not recorded.
+	if ctx.Err() == nil {
+		recordResponses(responses)
+	}
+	return responses
+}
@@ -0,0 +1,170 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/ghdwlsgur/gostat/internal"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// probeSpec is a url probed by the exporter, the target and headers override the flags.
+type probeSpec struct {
+	URL     string            `mapstructure:"url" json:"url"`
+	Target  string            `mapstructure:"target" json:"target,omitempty"`
+	Headers map[string]string `mapstructure:"headers" json:"headers,omitempty"`
+}
+
+// probeSpecs returns the probes of the config file, otherwise a probe per url argument.
+func probeSpecs(cmd *cobra.Command, args []string) ([]probeSpec, error) {
+	var specs []probeSpec
+
+	if len(args) == 0 && viper.IsSet("probes") {
+		if err := viper.UnmarshalKey("probes", &specs); err != nil {
+			return nil, err
+		}
+		return specs, nil
+	}
+
+	urls, err := edgeURLs(cmd, args)
+	if err != nil {
+		return nil, err
+	}
+	for _, url := range urls {
+		specs = append(specs, probeSpec{URL: url})
+	}
+	return specs, nil
+}
+
+// runProbe resolves the target of the probe and requests each of its edges.
+func runProbe(ctx context.Context, spec probeSpec) ([]*internal.Response, error) {
+	target := spec.Target
+	if target == "" {
+		target = viper.GetString("target-domain")
+	}
+
+	edge, err := parseEdgeTarget(ctx, spec.URL, target)
+	if err != nil {
+		return nil, err
+	}
+	edge.requestOptions.FullBody = true
+	for field, value := range spec.Headers {
+		edge.requestOptions.Header.Set(field, value)
+	}
+
+	return probeEdges(ctx, edge.ips, edge.addrInfo, edge.requestOptions, edge.protocol), nil
+}
+
+// observeProbe runs the probe and records its responses, or the failure to resolve its target.
+func observeProbe(ctx context.Context, metrics *internal.Metrics, spec probeSpec) {
+	responses, err := runProbe(ctx, spec)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, color.RedString("[err] %s: %s", spec.URL, err.Error()))
+		metrics.ObserveResolveError(spec.URL)
+		return
+	}
+	metrics.Observe(spec.URL, responses)
+}
+
+// listenAndServe serves the handler until the context is done, the requests in flight share
+// the context so that they are canceled with it.
+func listenAndServe(ctx context.Context, address string, handler http.Handler) error {
+	server := &http.Server{
+		Addr:        address,
+		Handler:     handler,
+		BaseContext: func(net.Listener) context.Context { return ctx },
+	}
+
+	go func() {
+		<-ctx.Done()
+		shutdown, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(shutdown)
+	}()
+
+	if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		return err
+	}
+	return nil
+}
+
+// probeHandler probes the url of the query once on every edge of the target, in the way of
+// the blackbox exporter, so that scrape configs with relabeling can drive the probes.
+func probeHandler(w http.ResponseWriter, r *http.Request) {
+	spec := probeSpec{
+		URL:    r.URL.Query().Get("url"),
+		Target: r.URL.Query().Get("target"),
+	}
+	if spec.URL == "" {
+		http.Error(w, "url parameter is missing", http.StatusBadRequest)
+		return
+	}
+
+	metrics := internal.NewMetrics()
+	observeProbe(r.Context(), metrics, spec)
+	metrics.ServeHTTP(w, r)
+}
+
+var (
+	exporterCommand = &cobra.Command{
+		Use:   "exporter",
+		Short: "Exec `gostat exporter --listen :9115 --config probes.yaml`",
+		Long:  "Probes each URL on every A record of its target on a schedule and serves the status code, latency of each step, body hash changes, certificate expiry and cache status of every edge as Prometheus metrics on /metrics. /probe?url=...&target=... probes a single URL when it is scraped.",
+		PreRun: func(cmd *cobra.Command, args []string) {
+			bindRequestFlags(cmd)
+			viper.BindPFlag("listen-address", cmd.Flags().Lookup("listen"))
+			viper.BindPFlag("exporter-interval", cmd.Flags().Lookup("interval"))
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			ctx := cmd.Context()
+			specs, err := probeSpecs(cmd, args)
+			if err != nil {
+				panicRed(err)
+			}
+
+			interval := viper.GetDuration("exporter-interval")
+			if interval <= 0 {
+				panicRed(fmt.Errorf("--interval must be greater than zero, got %s", interval))
+			}
+
+			metrics := internal.NewMetrics()
+			go func() {
+				ticker := time.NewTicker(interval)
+				defer ticker.Stop()
+				for {
+					for _, spec := range specs {
+						observeProbe(ctx, metrics, spec)
+					}
+					select {
+					case <-ticker.C:
+					case <-ctx.Done():
+						return
+					}
+				}
+			}()
+
+			mux := http.NewServeMux()
+			mux.Handle("/metrics", metrics)
+			mux.HandleFunc("/probe", probeHandler)
+			address := viper.GetString("listen-address")
+			fmt.Printf("%s %s\n", color.HiBlackString("Serving metrics on"), color.HiYellowString("%s/metrics", address))
+			if err := listenAndServe(ctx, address, mux); err != nil {
+				panicRed(err)
+			}
+		},
+	}
+)
+
+func init() {
+	addRequestFlags(exporterCommand)
+	exporterCommand.Flags().String("listen", ":9115", "[optional] Address the metrics are served on.")
+	exporterCommand.Flags().Duration("interval", 30*time.Second, "[optional] Time between two probes of the same url.")
+
+	rootCmd.AddCommand(exporterCommand)
+}
@@ -0,0 +1,113 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/ghdwlsgur/gostat/internal"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// historyMu serializes the records of the commands that probe concurrently, since the
+// history is locked by the first one until it is closed.
+var historyMu sync.Mutex
+
+// recordResponses appends the responses to the history of --record, if any. The history is
+// opened for each record and closed right after, so that it can be read while a long running
+// command records to it. A failure to record is reported but not fatal.
+func recordResponses(responses []*internal.Response) {
+	path := viper.GetString("record-path")
+	if path == "" {
+		return
+	}
+
+	now := time.Now()
+	records := make([]internal.HistoryRecord, 0, len(responses))
+	for _, response := range responses {
+		records = append(records, internal.NewHistoryRecord(now, response))
+	}
+
+	historyMu.Lock()
+	defer historyMu.Unlock()
+
+	history, err := internal.OpenHistory(path, false)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, color.RedString("[err] %s", err.Error()))
+		return
+	}
+	defer history.Close()
+
+	if err := history.Record(records); err != nil {
+		fmt.Fprintln(os.Stderr, color.RedString("[err] record history: %s", err.Error()))
+	}
+}
+
+// historyReport is the json output of the history command.
+type historyReport struct {
+	URL     string                   `json:"url"`
+	Records []internal.HistoryRecord `json:"records"`
+	Changes []internal.HistoryChange `json:"changes"`
+	Latency []internal.LatencyTrend  `json:"latency"`
+}
+
+var (
+	historyCommand = &cobra.Command{
+		Use:   "history",
+		Short: "Exec `gostat history https://domain.com --record history.db`",
+		Long:  "Reads the probes of the URL recorded with --record and reports the changes of status, hash and certificate of each edge with the trend of its latency over time.",
+		PreRun: func(cmd *cobra.Command, args []string) {
+			viper.BindPFlag("history-since", cmd.Flags().Lookup("since"))
+			viper.BindPFlag("history-interval", cmd.Flags().Lookup("interval"))
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			urls, err := edgeURLs(cmd, args)
+			if err != nil {
+				panicRed(err)
+			}
+			path := viper.GetString("record-path")
+			if path == "" {
+				panicRed(fmt.Errorf("--record is required to read the history"))
+			}
+
+			history, err := internal.OpenHistory(path, true)
+			if err != nil {
+				panicRed(err)
+			}
+			defer history.Close()
+
+			since := time.Now().Add(-viper.GetDuration("history-since"))
+			interval := viper.GetDuration("history-interval")
+
+			var reports []historyReport
+			for _, url := range urls {
+				records, err := history.Query(url, since)
+				if err != nil {
+					panicRed(err)
+				}
+				changes := internal.HistoryChanges(records)
+				trends := internal.LatencyTrends(records, interval)
+
+				if viper.GetString("output-format") == "json" {
+					reports = append(reports, historyReport{URL: url, Records: records, Changes: changes, Latency: trends})
+					continue
+				}
+				internal.PrintHistory(url, records, changes, trends)
+			}
+
+			if reports != nil {
+				printJSON(reports)
+			}
+		},
+	}
+)
+
+func init() {
+	historyCommand.Flags().Duration("since", 24*time.Hour, "[optional] how far back the history is read")
+	historyCommand.Flags().Duration("interval", time.Hour, "[optional] interval the latency is averaged over")
+
+	rootCmd.AddCommand(historyCommand)
+}
@@ -0,0 +1,64 @@
+package cmd
+
+import (
+	"os"
+	"time"
+
+	"github.com/ghdwlsgur/gostat/internal"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var (
+	purgeCheckCommand = &cobra.Command{
+		Use:   "purge-check",
+		Short: "Exec `gostat purge-check https://domain.com --since 2023-10-10T10:00:00Z`",
+		Long:  "Polls the URL on each A record of the target domain until Last-Modified, ETag or the body hash reflects the version published after the purge, or Age resets, and reports the convergence time of each edge and the edges still stale after the deadline.",
+		PreRun: func(cmd *cobra.Command, args []string) {
+			bindRequestFlags(cmd)
+			viper.BindPFlag("purge-since", cmd.Flags().Lookup("since"))
+			viper.BindPFlag("interval", cmd.Flags().Lookup("interval"))
+			viper.BindPFlag("deadline", cmd.Flags().Lookup("deadline"))
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			ctx := cmd.Context()
+			edge, err := parseEdgeArgs(cmd, args)
+			if err != nil {
+				panicRed(err)
+			}
+			edge.requestOptions.FullBody = true
+
+			condition, err := internal.ParsePurgeSince(viper.GetString("purge-since"))
+			if err != nil {
+				panicRed(err)
+			}
+			interval := viper.GetDuration("interval")
+			deadline := time.Now().Add(viper.GetDuration("deadline"))
+
+			ips := internal.SortIPs(edge.ips)
+			results := make([]internal.PurgeResult, len(ips))
+			// Every edge is polled at the same time so that the convergence times are comparable.
+			forEachEdge(ips, edge.addrInfo, len(ips), func(i int, addr *internal.Address) {
+				results[i] = internal.PollPurge(ctx, addr.IP, func() *internal.Response {
+					return fetchEdge(ctx, addr, edge.requestOptions, edge.protocol)
+				}, condition, interval, deadline)
+			})
+
+			internal.PrintPurgeResults(edge.rawURL, results)
+			for _, r := range results {
+				if !r.Converged {
+					os.Exit(1)
+				}
+			}
+		},
+	}
+)
+
+func init() {
+	addRequestFlags(purgeCheckCommand)
+	purgeCheckCommand.Flags().String("since", "", "[required] Time of the purge (RFC 3339 or HTTP date), or the ETag or body hash of the purged version.")
+	purgeCheckCommand.Flags().Duration("interval", 5*time.Second, "[optional] Time to wait between requests to the same edge.")
+	purgeCheckCommand.Flags().Duration("deadline", 5*time.Minute, "[optional] Time after which edges not converged are reported as stale.")
+
+	rootCmd.AddCommand(purgeCheckCommand)
+}
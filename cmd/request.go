@@ -1,12 +1,13 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"strings"
-	"sync"
 	"time"
 
+	"github.com/fatih/color"
 	"github.com/ghdwlsgur/gostat/internal"
 	ui "github.com/gizak/termui/v3"
 	"github.com/gizak/termui/v3/widgets"
@@ -84,6 +85,7 @@ func (d drawArgs) rendering() {
 }
 
 func (d drawArgs) insertData() {
+	// A failed edge shows the category of its error in place of the status code.
 	d.responseTable.Rows[1][d.index+1] = d.response.GetStatusCode()
 	d.responseTable.Rows[2][d.index+1] = d.response.GetServer()
 	d.responseTable.Rows[3][d.index+1] = d.response.GetDate()
@@ -96,16 +98,12 @@ func (d drawArgs) insertData() {
 	d.responseTable.Rows[10][d.index+1] = d.response.GetContentLength()
 	d.responseTable.Rows[11][d.index+1] = d.response.GetACAOrigin()
 	d.responseTable.Rows[12][d.index+1] = d.response.GetVia()
-	d.responseTable.Rows[13][d.index+1] = d.response.GetHash()
-	d.responseTable.Rows[14][d.index+1] = d.requestOptions.GetRequestCount()
+	d.responseTable.Rows[13][d.index+1] = d.response.GetCacheStatus()
+	d.responseTable.Rows[14][d.index+1] = d.response.GetHash()
+	d.responseTable.Rows[15][d.index+1] = d.requestOptions.GetRequestCount()
 }
 
-func showDashboard(ips []string, addrInfo *internal.Address, requestOptions *internal.ReqOptions, protocol string) error {
-	if err := ui.Init(); err != nil {
-		return err
-	}
-	defer ui.Close()
-
+func showDashboard(ctx context.Context, ips []string, addrInfo *internal.Address, requestOptions *internal.ReqOptions, protocol string) error {
 	if err := ui.Init(); err != nil {
 		return err
 	}
@@ -125,64 +123,59 @@ func showDashboard(ips []string, addrInfo *internal.Address, requestOptions *int
 	hashBox.data = append(hashBox.data, "Hash")
 	timeBox.data = append(timeBox.data, "Time")
 
-delay:
-	for {
-		select {
-		case e := <-uiEvents:
-			if e.Type == ui.KeyboardEvent && (e.ID == "q" || e.ID == "<C-c>") {
-				os.Exit(0)
-				break delay
-			}
-		default:
-			for i, ip := range ips {
-				addrInfo.IP = ip
-				requestOptions.Port = viper.GetInt("port-number")
-				switch protocol {
-				case "https":
-					response := internal.GetStatusCodeOnHTTPS(addrInfo, requestOptions)
-					if response.Error != nil {
-						return response.Error
-					}
-
-					widgetDraw(&drawArgs{
-						edgeCharts:             edgeCharts,
-						response:               response,
-						ip:                     ip,
-						ipListLength:           len(ips) - 1,
-						index:                  i,
-						responseTable:          responseTable,
-						statusCodeHistoryTable: statusCodeHistoryTable,
-						hashHistoryTable:       hashHistoryTable,
-						timeHistoryTable:       timeHistoryTable,
-						statusBox:              statusBox,
-						hashBox:                hashBox,
-						timeBox:                timeBox,
-						requestOptions:         requestOptions,
-					})
-				case "http":
-					response := internal.GetStatusCodeOnHTTP(addrInfo, requestOptions)
-					if response.Error != nil {
-						return response.Error
-					}
-
-					widgetDraw(&drawArgs{
-						edgeCharts:             edgeCharts,
-						response:               response,
-						ip:                     ip,
-						ipListLength:           len(ips) - 1,
-						index:                  i,
-						responseTable:          responseTable,
-						statusCodeHistoryTable: statusCodeHistoryTable,
-						hashHistoryTable:       hashHistoryTable,
-						timeHistoryTable:       timeHistoryTable,
-						statusBox:              statusBox,
-						hashBox:                hashBox,
-						timeBox:                timeBox,
-						requestOptions:         requestOptions,
-					})
+	// q or Ctrl-C cancels the request in flight and closes the dashboard, the events stop
+	// being read once the dashboard is closed.
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		for {
+			select {
+			case e := <-uiEvents:
+				if e.Type == ui.KeyboardEvent && (e.ID == "q" || e.ID == "<C-c>") {
+					cancel()
+					return
 				}
+			case <-ctx.Done():
+				return
 			}
 		}
+	}()
+
+	for ctx.Err() == nil {
+		responses := make([]*internal.Response, 0, len(ips))
+		for i, ip := range ips {
+			addrInfo.IP = ip
+			requestOptions.Port = viper.GetInt("port-number")
+
+			var response *internal.Response
+			switch protocol {
+			case "https":
+				response = internal.GetStatusCodeOnHTTPS(ctx, addrInfo, requestOptions)
+			case "http":
+				response = internal.GetStatusCodeOnHTTP(ctx, addrInfo, requestOptions)
+			}
+			if ctx.Err() != nil {
+				return nil
+			}
+			responses = append(responses, response)
+
+			widgetDraw(&drawArgs{
+				edgeCharts:             edgeCharts,
+				response:               response,
+				ip:                     ip,
+				ipListLength:           len(ips) - 1,
+				index:                  i,
+				responseTable:          responseTable,
+				statusCodeHistoryTable: statusCodeHistoryTable,
+				hashHistoryTable:       hashHistoryTable,
+				timeHistoryTable:       timeHistoryTable,
+				statusBox:              statusBox,
+				hashBox:                hashBox,
+				timeBox:                timeBox,
+				requestOptions:         requestOptions,
+			})
+		}
+		recordResponses(responses)
 		requestOptions.RequestCount++
 	}
 	return nil
@@ -228,13 +221,13 @@ func createHistoryTable(name string) *widgets.Table {
 	switch name {
 	case "statusCode":
 		historyTable.Title = "StatusCode History"
-		historyTable.SetRect(85, 31, 180, 34)
+		historyTable.SetRect(85, 33, 180, 36)
 	case "time":
 		historyTable.Title = "Time History"
-		historyTable.SetRect(85, 34, 180, 37)
+		historyTable.SetRect(85, 36, 180, 39)
 	case "hash":
 		historyTable.Title = "Hash History"
-		historyTable.SetRect(85, 37, 180, 40)
+		historyTable.SetRect(85, 39, 180, 42)
 	}
 
 	return historyTable
@@ -260,6 +253,7 @@ func createResponseTable(ips []string) *widgets.Table {
 		make([]string, len(ips)+1), // Content-Length
 		make([]string, len(ips)+1), // Access-Control-Allow-Origin
 		make([]string, len(ips)+1), // Via
+		make([]string, len(ips)+1), // Cache
 		make([]string, len(ips)+1), // Hash
 		make([]string, len(ips)+1), // RequestCount
 	}
@@ -277,15 +271,16 @@ func createResponseTable(ips []string) *widgets.Table {
 	responseTable.Rows[10][0] = "Content-Length"
 	responseTable.Rows[11][0] = "ACA-Origin"
 	responseTable.Rows[12][0] = "Via"
-	responseTable.Rows[13][0] = "Hash"
-	responseTable.Rows[14][0] = "RequestCount"
+	responseTable.Rows[13][0] = "Cache"
+	responseTable.Rows[14][0] = "Hash"
+	responseTable.Rows[15][0] = "RequestCount"
 	responseTable.BorderStyle.Fg = 7
 	responseTable.BorderStyle.Bg = 0
 	responseTable.TitleStyle.Fg = 7
 	responseTable.TitleStyle.Bg = 0
 	responseTable.TextStyle = ui.NewStyle(ui.ColorWhite)
 	responseTable.TextStyle.Bg = 0
-	responseTable.SetRect(85, 0, 180, 31)
+	responseTable.SetRect(85, 0, 180, 33)
 
 	return responseTable
 }
@@ -340,27 +335,39 @@ func getProtocol(data []string) (string, error) {
 	return data[0], nil
 }
 
-func reqHTTP(ips []string, addrInfo *internal.Address, requestOptions *internal.ReqOptions) error {
-	for _, ip := range ips {
-		addrInfo.IP = ip
-		requestOptions.Port = viper.GetInt("port-number")
-
-		err := internal.ResolveHTTP(addrInfo, requestOptions)
-		if err != nil {
-			return err
-		}
-	}
-	return nil
+func reqHTTP(ctx context.Context, ips []string, addrInfo *internal.Address, requestOptions *internal.ReqOptions) error {
+	return printEdges(ctx, probeEdges(ctx, ips, addrInfo, requestOptions, "http"), addrInfo, requestOptions)
 }
 
-func reqHTTPS(ips []string, addrInfo *internal.Address, requestOptions *internal.ReqOptions) error {
-	for _, ip := range ips {
-		addrInfo.IP = ip
+func reqHTTPS(ctx context.Context, ips []string, addrInfo *internal.Address, requestOptions *internal.ReqOptions) error {
+	return printEdges(ctx, probeEdges(ctx, ips, addrInfo, requestOptions, "https"), addrInfo, requestOptions)
+}
 
-		err := internal.ResolveHTTPS(addrInfo, requestOptions)
-		if err != nil {
-			return err
+// printEdges prints the response of each edge, and the category of the error of the edges
+// that failed so that the other edges are still shown. Once the context is done, the edges
+// that did not answer in time are listed instead.
+func printEdges(ctx context.Context, responses []*internal.Response, addrInfo *internal.Address, requestOptions *internal.ReqOptions) error {
+	var canceled []string
+	failed := 0
+	for _, response := range responses {
+		if response.Error != nil {
+			if ctx.Err() != nil {
+				canceled = append(canceled, response.EdgeIP)
+				continue
+			}
+			failed++
+			internal.PrintResponseError(addrInfo, requestOptions, response)
+			continue
 		}
+		internal.PrintResponse(addrInfo, requestOptions, response)
+	}
+
+	if len(canceled) > 0 {
+		fmt.Printf("\n%s %s\n", color.HiRedString("Canceled before answering:"), strings.Join(canceled, ", "))
+		return ctx.Err()
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d edges failed", failed, len(responses))
 	}
 	return nil
 }
@@ -388,158 +395,187 @@ func dynamicStatusCodeColor(statusCode int, sbcColor []ui.Color) []ui.Color {
 	return sbcColor
 }
 
+// runDashboard draws the response of each edge on the dashboard until it is closed.
+func runDashboard(ctx context.Context, edge *edgeArgs) {
+	edge.requestOptions.RequestCount++
+	edge.addrInfo.IP = edge.addrInfo.Target
+
+	if err := showDashboard(ctx, edge.ips, edge.addrInfo, edge.requestOptions, edge.protocol); err != nil {
+		panicRed(err)
+	}
+}
+
+// runBenchmark requests the edges in turn at the rate and for as long as the flags allow,
+// then prints the summary of the responses.
+func runBenchmark(ctx context.Context, edge *edgeArgs) error {
+	options := internal.BenchmarkOptions{
+		Rate:        viper.GetFloat64("benchmark-rate"),
+		Duration:    viper.GetDuration("benchmark-duration"),
+		Requests:    viper.GetInt("benchmark-requests"),
+		Concurrency: viper.GetInt("thread-count"),
+	}
+	// The default duration does not cut short a number of requests given without a duration.
+	if options.Requests > 0 && !viper.IsSet("benchmark-duration") {
+		options.Duration = 0
+	}
+	benchmark, err := internal.NewBenchmark(options, internal.SortIPs(edge.ips), func(ctx context.Context, ip string) *internal.Response {
+		addr := *edge.addrInfo
+		addr.IP = ip
+		return fetchEdge(ctx, &addr, edge.requestOptions, edge.protocol)
+	})
+	if err != nil {
+		return err
+	}
+
+	if viper.GetString("output-format") == "json" {
+		printJSON(benchmark.Run(ctx, edge.rawURL).Report())
+		return nil
+	}
+
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(500 * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				fmt.Printf("\r%s: %d", color.HiBlackString("Request Count"), benchmark.Sent())
+			case <-done:
+				return
+			}
+		}
+	}()
+	summary := benchmark.Run(ctx, edge.rawURL)
+	close(done)
+
+	fmt.Println()
+	internal.PrintBenchmark(summary)
+	return nil
+}
+
 var (
 	requestCommand = &cobra.Command{
 		Use:   "request",
 		Short: "Exec `gostat request https://domain.com -t domain.com`",
 		Long:  "Receives the response of the URL to each A record of the target domain to the url using the http or https protocol.",
+		PreRun: func(cmd *cobra.Command, args []string) {
+			bindRequestFlags(cmd)
+		},
 		Run: func(cmd *cobra.Command, args []string) {
-			var (
-				err        error
-				url        string
-				domainName string
-				target     string
-			)
-
-			var (
-				host          string
-				referer       string
-				authorization string
-			)
-
-			if err := cobra.MinimumNArgs(1)(cmd, args); err != nil {
-				panicRed(err)
-			}
-
-			if len(args) > 1 {
-				panicRed(fmt.Errorf("up to one argument can be entered"))
-			}
-			splitData := strings.Split(args[0], "://")
-
-			// Check the url format.
-			protocol, err := getProtocol(splitData)
+			ctx := cmd.Context()
+			urls, err := edgeURLs(cmd, args)
 			if err != nil {
 				panicRed(err)
 			}
 
-			url = splitData[1]
-			host = strings.TrimSpace(viper.GetString("host-name"))
-			referer = strings.TrimSpace(viper.GetString("referer-name"))
-			authorization = strings.TrimSpace(viper.GetString("authorization-name"))
 			mode := viper.GetBool("attack-mode")
 			dashboard := viper.GetBool("dashboard-mode")
-			domainName = strings.Split(url, "/")[0]
-			target = strings.TrimSpace(viper.GetString("target-domain"))
-			if target == "" {
-				target = domainName
-			}
-
-			ips, err := internal.GetRecordIPv4(target)
-			if err != nil {
-				panicRed(err)
-			}
-
-			// ! [required] Enter your address information.
-			addrInfo := &internal.Address{
-				Url:        url,
-				DomainName: domainName,
-				Target:     target,
-			}
-
-			// [optional] It is additionally saved when entering a header or referrer.
-			requestOptions := &internal.ReqOptions{
-				Host:          host,
-				Referer:       referer,
-				Authorization: authorization,
-				AttackMode:    mode,
+			if len(urls) > 1 && (mode || dashboard) {
+				panicRed(fmt.Errorf("up to one argument can be entered in benchmark or dashboard mode"))
 			}
 
-			if dashboard {
-				var wg sync.WaitGroup
-				for i := 0; i < 1; i++ {
-					wg.Add(1)
-					go func() {
-						defer wg.Done()
-						for {
-							requestOptions.RequestCount++
-							addrInfo.IP = target
-
-							err = showDashboard(ips, addrInfo, requestOptions, protocol)
-							if err != nil {
-								panicRed(err)
-							}
-						}
-					}()
+			var reports []internal.ResponseReport
+			var reuseReports []internal.ReuseReport
+			var resumptionReports []internal.ResumptionReport
+			valid := true
+			for _, url := range urls {
+				edge, err := parseEdgeURL(ctx, url)
+				if err != nil {
+					// The other urls are still requested when the target of one does not resolve.
+					fmt.Println(color.RedString("[err] %s: %s", url, err.Error()))
+					valid = false
+					continue
 				}
-				wg.Wait()
-			}
-
-			if mode {
-				var wg sync.WaitGroup
-				for i := 0; i < viper.GetInt("thread-count"); i++ {
-					wg.Add(1)
-					go func() {
-						defer wg.Done()
-						for {
-							requestOptions.RequestCount++
-							addrInfo.IP = target
-
-							if protocol == "http" {
-								err = reqHTTP(ips, addrInfo, requestOptions)
-								if err != nil {
-									panicRed(err)
-								}
-							}
-
-							if protocol == "https" {
-								err = reqHTTPS(ips, addrInfo, requestOptions)
-								if err != nil {
-									panicRed(err)
-								}
-							}
+				edge.requestOptions.AttackMode = mode
+
+				switch {
+				case viper.GetBool("revalidate-mode"):
+					results := revalidateEdges(ctx, edge)
+					internal.PrintRevalidation(edge.rawURL, results)
+					for _, r := range results {
+						valid = valid && r.Valid()
+					}
+				case viper.GetBool("resumption-mode"):
+					if edge.protocol != "https" {
+						panicRed(fmt.Errorf("TLS session resumption needs an https url"))
+					}
+					results := resumeEdges(ctx, edge)
+					if viper.GetString("output-format") == "json" {
+						for _, r := range results {
+							resumptionReports = append(resumptionReports, r.Report())
 						}
-					}()
-				}
-				wg.Wait()
-			} else {
-
-				if protocol == "http" {
-					err = reqHTTP(ips, addrInfo, requestOptions)
-					if err != nil {
-						panicRed(err)
+						break
 					}
-				}
-
-				if protocol == "https" {
-					err = reqHTTPS(ips, addrInfo, requestOptions)
-					if err != nil {
-						panicRed(err)
+					internal.PrintResumption(edge.rawURL, results)
+				case viper.GetInt("reuse-count") > 0:
+					results := reuseEdges(ctx, edge, viper.GetInt("reuse-count"))
+					if viper.GetString("output-format") == "json" {
+						for _, r := range results {
+							reuseReports = append(reuseReports, r.Report())
+						}
+						break
+					}
+					internal.PrintReuse(edge.rawURL, results)
+				case dashboard:
+					runDashboard(ctx, edge)
+				case mode:
+					if err := runBenchmark(ctx, edge); err != nil {
+						fmt.Println(color.RedString("[err] %s", err.Error()))
+					}
+				case viper.GetString("output-format") == "json":
+					for _, response := range probeEdges(ctx, edge.ips, edge.addrInfo, edge.requestOptions, edge.protocol) {
+						reports = append(reports, response.Report())
+					}
+				case edge.protocol == "http":
+					if err := reqHTTP(ctx, edge.ips, edge.addrInfo, edge.requestOptions); err != nil {
+						fmt.Println(color.RedString("[err] %s", err.Error()))
+						valid = false
+					}
+				case edge.protocol == "https":
+					if err := reqHTTPS(ctx, edge.ips, edge.addrInfo, edge.requestOptions); err != nil {
+						fmt.Println(color.RedString("[err] %s", err.Error()))
+						valid = false
 					}
 				}
 			}
 
+			if reports != nil {
+				printJSON(reports)
+			}
+			if reuseReports != nil {
+				printJSON(reuseReports)
+			}
+			if resumptionReports != nil {
+				printJSON(resumptionReports)
+			}
+			if !valid {
+				os.Exit(1)
+			}
 		},
 	}
 )
 
 func init() {
-	requestCommand.Flags().StringP("target", "t", "", "[required] Receive responses by proxying the A record of the domain forwarded to the target.")
-	requestCommand.Flags().IntP("port", "p", 80, "[optional] For http protocol, the default value is 80.")
+	addRequestFlags(requestCommand)
 	requestCommand.Flags().IntP("thread", "n", 1, "[optional] choose thread numbers")
-	requestCommand.Flags().StringP("host", "H", "", "[optional] The host to put in the request headers.")
-	requestCommand.Flags().StringP("authorization", "A", "", "[optional]")
-	requestCommand.Flags().StringP("referer", "r", "", "[optional]")
-	requestCommand.Flags().BoolP("attack", "a", false, "[optional] enable attack mode")
+	requestCommand.Flags().BoolP("attack", "a", false, "[optional] benchmark the edges at --rate for --duration or --requests")
+	requestCommand.Flags().Float64("rate", 10, "[optional] requests per second of the benchmark over every edge, unlimited when zero")
+	requestCommand.Flags().Duration("duration", 10*time.Second, "[optional] duration of the benchmark, unlimited when zero, and unlimited by default with --requests")
+	requestCommand.Flags().Int("requests", 0, "[optional] number of requests of the benchmark, unlimited when zero")
 	requestCommand.Flags().BoolP("dashboard", "d", false, "[optional] enable dashboard")
+	requestCommand.Flags().Int("reuse", 0, "[optional] send this many requests in turn to each edge over a single kept-alive connection")
+	requestCommand.Flags().Bool("resumption", false, "[optional] resume the TLS session of each edge on itself and on another edge, 0-RTT early data is not tested as the Go TLS client does not send it")
+	requestCommand.Flags().Bool("revalidate", false, "[optional] re-request each edge with If-None-Match and If-Modified-Since and expect a 304")
 
-	viper.BindPFlag("target-domain", requestCommand.Flags().Lookup("target"))
-	viper.BindPFlag("port-number", requestCommand.Flags().Lookup("port"))
-	viper.BindPFlag("host-name", requestCommand.Flags().Lookup("host"))
-	viper.BindPFlag("authorization-name", requestCommand.Flags().Lookup("authorization"))
-	viper.BindPFlag("referer-name", requestCommand.Flags().Lookup("referer"))
 	viper.BindPFlag("attack-mode", requestCommand.Flags().Lookup("attack"))
 	viper.BindPFlag("thread-count", requestCommand.Flags().Lookup("thread"))
+	viper.BindPFlag("benchmark-rate", requestCommand.Flags().Lookup("rate"))
+	viper.BindPFlag("benchmark-duration", requestCommand.Flags().Lookup("duration"))
+	viper.BindPFlag("benchmark-requests", requestCommand.Flags().Lookup("requests"))
 	viper.BindPFlag("dashboard-mode", requestCommand.Flags().Lookup("dashboard"))
+	viper.BindPFlag("revalidate-mode", requestCommand.Flags().Lookup("revalidate"))
+	viper.BindPFlag("reuse-count", requestCommand.Flags().Lookup("reuse"))
+	viper.BindPFlag("resumption-mode", requestCommand.Flags().Lookup("resumption"))
 
 	rootCmd.AddCommand(requestCommand)
 }
@@ -0,0 +1,41 @@
+package cmd
+
+import (
+	"context"
+	"crypto/tls"
+
+	"github.com/ghdwlsgur/gostat/internal"
+	"github.com/spf13/viper"
+)
+
+// resumeEdges makes a full handshake to each edge, then resumes its session on the same edge
+// and on the next edge, to tell whether the edges share their session tickets.
+func resumeEdges(ctx context.Context, edge *edgeArgs) []internal.ResumptionResult {
+	edge.requestOptions.FullBody = true
+
+	ips := internal.SortIPs(edge.ips)
+	results := make([]internal.ResumptionResult, len(ips))
+	caches := make([]tls.ClientSessionCache, len(ips))
+
+	// Every edge has a session before any is resumed, so that it can be resumed on another edge.
+	forEachEdge(ips, edge.addrInfo, viper.GetInt("concurrency-count"), func(i int, addr *internal.Address) {
+		caches[i] = tls.NewLRUClientSessionCache(1)
+		results[i] = internal.FullHandshake(ctx, addr, edge.requestOptions, caches[i])
+	})
+
+	forEachEdge(ips, edge.addrInfo, viper.GetInt("concurrency-count"), func(i int, addr *internal.Address) {
+		if !results[i].Handshaked() {
+			return
+		}
+		results[i].Resume = internal.ResumeHandshake(ctx, addr, edge.requestOptions, caches[i])
+
+		other := (i + 1) % len(ips)
+		if other == i || !results[other].Handshaked() {
+			return
+		}
+		results[i].CrossEdge = ips[other]
+		results[i].Cross = internal.CrossEdgeHandshake(ctx, addr, edge.requestOptions, caches[other])
+	})
+
+	return results
+}
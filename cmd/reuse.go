@@ -0,0 +1,21 @@
+package cmd
+
+import (
+	"context"
+
+	"github.com/ghdwlsgur/gostat/internal"
+	"github.com/spf13/viper"
+)
+
+// reuseEdges sends count requests in turn to each edge, each over its own kept-alive connection.
+func reuseEdges(ctx context.Context, edge *edgeArgs, count int) []internal.ReuseResult {
+	edge.requestOptions.FullBody = true
+
+	ips := internal.SortIPs(edge.ips)
+	results := make([]internal.ReuseResult, len(ips))
+	forEachEdge(ips, edge.addrInfo, viper.GetInt("concurrency-count"), func(i int, addr *internal.Address) {
+		results[i] = internal.ProbeReuse(ctx, edge.protocol, addr, edge.requestOptions, count)
+	})
+
+	return results
+}
@@ -0,0 +1,35 @@
+package cmd
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/ghdwlsgur/gostat/internal"
+	"github.com/spf13/viper"
+)
+
+// revalidateEdges requests each edge and immediately requests it again with the validators of the response.
+func revalidateEdges(ctx context.Context, edge *edgeArgs) []internal.RevalidateResult {
+	edge.requestOptions.FullBody = true
+
+	ips := internal.SortIPs(edge.ips)
+	results := make([]internal.RevalidateResult, len(ips))
+	forEachEdge(ips, edge.addrInfo, viper.GetInt("concurrency-count"), func(i int, addr *internal.Address) {
+		first := fetchEdge(ctx, addr, edge.requestOptions, edge.protocol)
+
+		var conditional *internal.Response
+		if header := internal.ConditionalHeader(first); first.Error == nil && len(header) > 0 {
+			requestOptions := edge.requestOptions.Clone()
+			if requestOptions.Header == nil {
+				requestOptions.Header = http.Header{}
+			}
+			for field, values := range header {
+				requestOptions.Header[field] = values
+			}
+			conditional = fetchEdge(ctx, addr, requestOptions, edge.protocol)
+		}
+		results[i] = internal.CheckRevalidation(first, conditional)
+	})
+
+	return results
+}
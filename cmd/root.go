@@ -1,11 +1,19 @@
 package cmd
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
 
 	"github.com/fatih/color"
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
 )
 
 var (
@@ -14,20 +22,69 @@ var (
 		Use:   "gostat",
 		Short: `gostat is an interactive CLI tool that proxies the A record of the input domain as a target and returns the response value received from the input URL.`,
 		Long:  `gostat is an interactive CLI tool that proxies the A record of the input domain as a target and returns the response value received from the input URL. It can also be used to check latency or to check whether each option is applied to the URL by adding headers and referrers to the request header.`,
+		PersistentPreRun: func(cmd *cobra.Command, args []string) {
+			if maxTime := viper.GetDuration("max-time"); maxTime > 0 {
+				ctx, cancel := context.WithTimeout(cmd.Context(), maxTime)
+				cmd.SetContext(ctx)
+				stopMaxTime = cancel
+			}
+		},
 	}
 )
 
+// profileKeys maps the keys of a profile in the config file to the keys the commands read.
+var profileKeys = map[string]string{
+	"target":          "target-domain",
+	"port":            "port-number",
+	"host":            "host-name",
+	"referer":         "referer-name",
+	"authorization":   "authorization-name",
+	"concurrency":     "concurrency-count",
+	"headers":         "request-headers",
+	"resolver":        "resolver-address",
+	"output":          "output-format",
+	"thresholds":      "thresholds",
+	"urls":            "urls",
+	"checks":          "checks",
+	"probes":          "probes",
+	"timeout":         "request-timeout",
+	"connect-timeout": "connect-timeout",
+	"max-time":        "max-time",
+	"retries":         "retries",
+	"retry-backoff":   "retry-backoff",
+	"retry-on":        "retry-on",
+	"record":          "record-path",
+}
+
+// stopMaxTime releases the deadline of --max-time once the command returned.
+var stopMaxTime context.CancelFunc = func() {}
+
 // panicRed raises error with text.
 func panicRed(err error) {
 	fmt.Println(color.RedString("[err] %s", err.Error()))
 	os.Exit(1)
 }
 
+// printJSON writes the value as indented json to the standard output.
+func printJSON(v interface{}) {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		panicRed(err)
+	}
+	fmt.Println(string(data))
+}
+
 // Execute adds all child commands to the root command and sets flags appropriately.
 // This is called by main.main(). It only needs to happen once to the rootCmd.
 func Execute(version string) {
 	rootCmd.Version = version
-	if err := rootCmd.Execute(); err != nil {
+
+	// Ctrl-C cancels the requests in flight so that the command can report what it has so far.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	defer func() { stopMaxTime() }()
+
+	if err := rootCmd.ExecuteContext(ctx); err != nil {
 		panicRed(err)
 	}
 }
@@ -39,8 +96,88 @@ func initConfig() {
 	if err != nil {
 		panicRed(err)
 	}
+
+	bindEnv()
+
+	if configFile := viper.GetString("config-file"); configFile != "" {
+		viper.SetConfigFile(configFile)
+	} else {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			panicRed(err)
+		}
+		viper.AddConfigPath(filepath.Join(home, ".config", "gostat"))
+		viper.SetConfigName("config")
+		viper.SetConfigType("yaml")
+	}
+
+	if err := viper.ReadInConfig(); err != nil {
+		// The config file is optional unless it is given explicitly.
+		if _, ok := err.(viper.ConfigFileNotFoundError); !ok || viper.GetString("config-file") != "" {
+			panicRed(err)
+		}
+	}
+
+	if err := applyProfile(viper.GetString("profile")); err != nil {
+		panicRed(err)
+	}
+}
+
+// bindEnv lets GOSTAT_TARGET, GOSTAT_HOST, ... override the values of the config file.
+func bindEnv() {
+	viper.SetEnvPrefix("GOSTAT")
+	viper.SetEnvKeyReplacer(strings.NewReplacer("-", "_", ".", "_"))
+	viper.AutomaticEnv()
+	for key, viperKey := range profileKeys {
+		viper.BindEnv(viperKey, "GOSTAT_"+strings.ToUpper(strings.ReplaceAll(key, "-", "_")))
+	}
+}
+
+// applyProfile merges the values of the named profile over the config file, so that they
+// take precedence over the top-level values of the file while flags and environment
+// variables still take precedence over them.
+func applyProfile(name string) error {
+	if name == "" {
+		return nil
+	}
+
+	profile := viper.Sub("profiles." + name)
+	if profile == nil {
+		return fmt.Errorf("profile %s is not found in the config file", name)
+	}
+
+	values := map[string]interface{}{}
+	for key, viperKey := range profileKeys {
+		if profile.IsSet(key) {
+			values[viperKey] = profile.Get(key)
+		}
+	}
+	return viper.MergeConfigMap(values)
 }
 
 func init() {
 	cobra.OnInitialize(initConfig)
+
+	rootCmd.PersistentFlags().String("config", "", "[optional] config file (default is $HOME/.config/gostat/config.yaml)")
+	rootCmd.PersistentFlags().String("profile", "", "[optional] named profile of the config file to apply")
+	rootCmd.PersistentFlags().String("output", "text", "[optional] output format (text, json, and junit or tap for check)")
+
+	viper.BindPFlag("config-file", rootCmd.PersistentFlags().Lookup("config"))
+	viper.BindPFlag("profile", rootCmd.PersistentFlags().Lookup("profile"))
+	rootCmd.PersistentFlags().Duration("timeout", 30*time.Second, "[optional] maximum time of a request to an edge, including reading the body")
+	rootCmd.PersistentFlags().Duration("connect-timeout", 5*time.Second, "[optional] maximum time of connecting to an edge, including the TLS handshake")
+	rootCmd.PersistentFlags().Duration("max-time", 0, "[optional] maximum time of the whole command, unlimited when zero")
+	rootCmd.PersistentFlags().String("record", "", "[optional] append every probe result, except those of a benchmark, to the history database at this file or directory")
+	rootCmd.PersistentFlags().Int("retries", 0, "[optional] number of times a request to an edge is retried")
+	rootCmd.PersistentFlags().Duration("retry-backoff", 500*time.Millisecond, "[optional] wait before the first retry, doubled after each retry")
+	rootCmd.PersistentFlags().StringSlice("retry-on", []string{"5xx", "timeout", "reset"}, "[optional] responses that are retried: status classes or codes, timeout, reset or error categories")
+
+	viper.BindPFlag("output-format", rootCmd.PersistentFlags().Lookup("output"))
+	viper.BindPFlag("request-timeout", rootCmd.PersistentFlags().Lookup("timeout"))
+	viper.BindPFlag("connect-timeout", rootCmd.PersistentFlags().Lookup("connect-timeout"))
+	viper.BindPFlag("max-time", rootCmd.PersistentFlags().Lookup("max-time"))
+	viper.BindPFlag("record-path", rootCmd.PersistentFlags().Lookup("record"))
+	viper.BindPFlag("retries", rootCmd.PersistentFlags().Lookup("retries"))
+	viper.BindPFlag("retry-backoff", rootCmd.PersistentFlags().Lookup("retry-backoff"))
+	viper.BindPFlag("retry-on", rootCmd.PersistentFlags().Lookup("retry-on"))
 }
@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+// Testing that flags take precedence over environment variables, which take precedence
+// over the profile, which takes precedence over the top-level keys of the config file.
+func TestProfilePrecedence(t *testing.T) {
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+
+	config := `
+target-domain: file.com
+host-name: file.com
+port-number: 8000
+resolver-address: file:53
+profiles:
+  staging:
+    target: profile.com
+    host: profile.com
+    port: 8080
+    thresholds:
+      latency: 500ms
+`
+	viper.SetConfigType("yaml")
+	if err := viper.ReadConfig(strings.NewReader(config)); err != nil {
+		t.Fatal(err)
+	}
+
+	flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	flags.StringP("target", "t", "", "")
+	flags.String("host", "", "")
+	viper.BindPFlag("target-domain", flags.Lookup("target"))
+	viper.BindPFlag("host-name", flags.Lookup("host"))
+	if err := flags.Parse([]string{"--target", "flag.com"}); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("GOSTAT_TARGET", "env.com")
+	t.Setenv("GOSTAT_HOST", "env.com")
+	bindEnv()
+
+	if err := applyProfile("staging"); err != nil {
+		t.Fatal(err)
+	}
+
+	for key, want := range map[string]string{
+		"target-domain":      "flag.com",
+		"host-name":          "env.com",
+		"port-number":        "8080",
+		"resolver-address":   "file:53",
+		"thresholds.latency": "500ms",
+	} {
+		if got := viper.GetString(key); got != want {
+			t.Errorf("%s: expected %s, got %s", key, want, got)
+		}
+	}
+
+	if err := applyProfile("production"); err == nil {
+		t.Error("expected an error for a profile missing from the config file")
+	}
+}
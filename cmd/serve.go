@@ -0,0 +1,199 @@
+package cmd
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/ghdwlsgur/gostat/internal"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// States of a probe job of the api.
+const (
+	jobRunning = "running"
+	jobDone    = "done"
+	jobFailed  = "failed"
+)
+
+// probeJob is a probe requested through the api, the results are those of the json output.
+type probeJob struct {
+	ID       string                    `json:"id"`
+	Status   string                    `json:"status"`
+	Probe    probeSpec                 `json:"probe"`
+	Created  time.Time                 `json:"created"`
+	Finished *time.Time                `json:"finished,omitempty"`
+	Error    string                    `json:"error,omitempty"`
+	Results  []internal.ResponseReport `json:"results,omitempty"`
+}
+
+// probeStore keeps the jobs of the api in memory until the retention after they finished,
+// and runs at most as many jobs at once as it has slots.
+type probeStore struct {
+	ctx       context.Context
+	mu        sync.Mutex
+	jobs      map[string]*probeJob
+	retention time.Duration
+	slots     chan struct{}
+}
+
+func newJobID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// start registers a job for the probe and runs it in the background, it reports false
+// without starting the job when every slot is taken by a running job.
+func (s *probeStore) start(spec probeSpec) (probeJob, bool) {
+	select {
+	case s.slots <- struct{}{}:
+	default:
+		return probeJob{}, false
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for id, job := range s.jobs {
+		if job.Finished != nil && now.Sub(*job.Finished) > s.retention {
+			delete(s.jobs, id)
+		}
+	}
+
+	job := &probeJob{ID: newJobID(), Status: jobRunning, Probe: spec, Created: now}
+	s.jobs[job.ID] = job
+
+	go func() {
+		defer func() { <-s.slots }()
+		responses, err := runProbe(s.ctx, spec)
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		finished := time.Now()
+		job.Finished = &finished
+		if err != nil {
+			job.Status, job.Error = jobFailed, err.Error()
+			return
+		}
+		job.Status = jobDone
+		job.Results = []internal.ResponseReport{}
+		for _, response := range responses {
+			job.Results = append(job.Results, response.Report())
+		}
+	}()
+
+	return *job, true
+}
+
+// get returns a copy of the job so that it can be encoded without holding the lock.
+func (s *probeStore) get(id string) (probeJob, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job, ok := s.jobs[id]
+	if !ok {
+		return probeJob{}, false
+	}
+	return *job, true
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeJSONError(w http.ResponseWriter, status int, format string, args ...interface{}) {
+	writeJSON(w, status, map[string]string{"error": fmt.Sprintf(format, args...)})
+}
+
+// ServeHTTP routes POST /v1/probes and GET /v1/probes/{id}.
+func (s *probeStore) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	id := strings.Trim(strings.TrimPrefix(r.URL.Path, "/v1/probes"), "/")
+
+	switch {
+	case id == "" && r.Method == http.MethodPost:
+		var spec probeSpec
+		if err := json.NewDecoder(r.Body).Decode(&spec); err != nil {
+			writeJSONError(w, http.StatusBadRequest, "invalid probe: %s", err)
+			return
+		}
+		if spec.URL == "" {
+			writeJSONError(w, http.StatusBadRequest, "url is missing")
+			return
+		}
+		job, ok := s.start(spec)
+		if !ok {
+			writeJSONError(w, http.StatusTooManyRequests, "the maximum of %d running probes is reached", cap(s.slots))
+			return
+		}
+		w.Header().Set("Location", "/v1/probes/"+job.ID)
+		writeJSON(w, http.StatusAccepted, job)
+
+	case id != "" && r.Method == http.MethodGet:
+		job, ok := s.get(id)
+		if !ok {
+			writeJSONError(w, http.StatusNotFound, "probe %s not found", id)
+			return
+		}
+		writeJSON(w, http.StatusOK, job)
+
+	default:
+		writeJSONError(w, http.StatusMethodNotAllowed, "%s %s is not supported", r.Method, r.URL.Path)
+	}
+}
+
+var (
+	serveCommand = &cobra.Command{
+		Use:   "serve",
+		Short: "Exec `gostat serve --listen :8080`",
+		Long:  "Serves a JSON API that probes a URL on every A record of its target in the background. POST /v1/probes with the url, target and headers returns the id of the probe, and GET /v1/probes/{id} returns its status and the results of each edge as in the json output.",
+		PreRun: func(cmd *cobra.Command, args []string) {
+			bindRequestFlags(cmd)
+			viper.BindPFlag("listen-address", cmd.Flags().Lookup("listen"))
+			viper.BindPFlag("retention", cmd.Flags().Lookup("retention"))
+			viper.BindPFlag("max-jobs", cmd.Flags().Lookup("max-jobs"))
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			ctx := cmd.Context()
+			maxJobs := viper.GetInt("max-jobs")
+			if maxJobs < 1 {
+				maxJobs = 1
+			}
+			store := &probeStore{
+				ctx:       ctx,
+				jobs:      map[string]*probeJob{},
+				retention: viper.GetDuration("retention"),
+				slots:     make(chan struct{}, maxJobs),
+			}
+			mux := http.NewServeMux()
+			mux.Handle("/v1/probes", store)
+			mux.Handle("/v1/probes/", store)
+
+			address := viper.GetString("listen-address")
+			fmt.Printf("%s %s\n", color.HiBlackString("Serving api on"), color.HiYellowString("%s/v1/probes", address))
+			if err := listenAndServe(ctx, address, mux); err != nil {
+				panicRed(err)
+			}
+		},
+	}
+)
+
+func init() {
+	addRequestFlags(serveCommand)
+	serveCommand.Flags().String("listen", ":8080", "[optional] Address the api is served on.")
+	serveCommand.Flags().Duration("retention", time.Hour, "[optional] Time the result of a probe is kept after it finished.")
+	serveCommand.Flags().Int("max-jobs", 16, "[optional] Maximum number of probes running at once, further probes are refused with 429.")
+
+	rootCmd.AddCommand(serveCommand)
+}
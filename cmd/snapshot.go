@@ -0,0 +1,106 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/ghdwlsgur/gostat/internal"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var (
+	snapshotCommand = &cobra.Command{
+		Use:   "snapshot",
+		Short: "Exec `gostat snapshot https://domain.com -t domain.com -o before.json`",
+		Long:  "Captures the status, headers, body hash and certificate of every edge for each URL, to be diffed against a later snapshot with the diff command.",
+		PreRun: func(cmd *cobra.Command, args []string) {
+			bindRequestFlags(cmd)
+			viper.BindPFlag("snapshot-file", cmd.Flags().Lookup("out"))
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			ctx := cmd.Context()
+			urls, err := edgeURLs(cmd, args)
+			if err != nil {
+				panicRed(err)
+			}
+
+			snapshot := internal.Snapshot{Time: time.Now()}
+			for _, url := range urls {
+				edge, err := parseEdgeURL(ctx, url)
+				if err != nil {
+					panicRed(err)
+				}
+				edge.requestOptions.FullBody = true
+
+				snapshotURL := internal.SnapshotURL{URL: edge.rawURL, Target: edge.addrInfo.Target}
+				for _, response := range probeEdges(ctx, edge.ips, edge.addrInfo, edge.requestOptions, edge.protocol) {
+					snapshotURL.Edges = append(snapshotURL.Edges, response.Report())
+				}
+				snapshot.URLs = append(snapshot.URLs, snapshotURL)
+			}
+			if err := ctx.Err(); err != nil {
+				panicRed(fmt.Errorf("snapshot is incomplete: %w", err))
+			}
+
+			path := viper.GetString("snapshot-file")
+			if path == "" {
+				printJSON(snapshot)
+				return
+			}
+			data, err := json.MarshalIndent(snapshot, "", "  ")
+			if err != nil {
+				panicRed(err)
+			}
+			if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+				panicRed(err)
+			}
+		},
+	}
+
+	diffCommand = &cobra.Command{
+		Use:   "diff",
+		Short: "Exec `gostat diff before.json after.json`",
+		Long:  "Reports what changed between two snapshots for each URL and edge: added and removed edges, status, body hash, certificate and header value changes. Exits with 1 when anything changed.",
+		Args:  cobra.ExactArgs(2),
+		PreRun: func(cmd *cobra.Command, args []string) {
+			viper.BindPFlag("ignore-header", cmd.Flags().Lookup("ignore-header"))
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			before, err := internal.ReadSnapshot(args[0])
+			if err != nil {
+				panicRed(err)
+			}
+			after, err := internal.ReadSnapshot(args[1])
+			if err != nil {
+				panicRed(err)
+			}
+
+			changes := internal.DiffSnapshots(before, after, viper.GetStringSlice("ignore-header"))
+			if viper.GetString("output-format") == "json" {
+				if changes == nil {
+					changes = []internal.SnapshotChange{}
+				}
+				printJSON(changes)
+			} else {
+				internal.PrintSnapshotDiff(before, after, changes)
+			}
+
+			if len(changes) > 0 {
+				os.Exit(1)
+			}
+		},
+	}
+)
+
+func init() {
+	addRequestFlags(snapshotCommand)
+	snapshotCommand.Flags().StringP("out", "o", "", "[optional] file the snapshot is written to, the standard output when empty")
+
+	diffCommand.Flags().StringSlice("ignore-header", nil, "[optional] headers that are not diffed, in addition to those that change on every response")
+
+	rootCmd.AddCommand(snapshotCommand)
+	rootCmd.AddCommand(diffCommand)
+}
@@ -0,0 +1,86 @@
+package cmd
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/ghdwlsgur/gostat/internal"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// varyResult holds the baseline and the compared variations of a single edge.
+type varyResult struct {
+	baseline   *internal.Response
+	variations []internal.VariationResult
+}
+
+// probeVariations warms the baseline of the edge and then sends each variation once.
+func probeVariations(ctx context.Context, addr *internal.Address, edge *edgeArgs, variations []internal.Variation) varyResult {
+	// The first request stores the baseline in the cache, the second one should hit it.
+	fetchEdge(ctx, addr, edge.requestOptions, edge.protocol)
+	result := varyResult{baseline: fetchEdge(ctx, addr, edge.requestOptions, edge.protocol)}
+	if result.baseline.Error != nil {
+		return result
+	}
+
+	for _, v := range variations {
+		requestOptions := edge.requestOptions.Clone()
+		for field, values := range v.Header {
+			requestOptions.Header[field] = values
+		}
+
+		varied := *addr
+		if v.Query != "" {
+			varied.Url = v.Query
+		}
+
+		response := fetchEdge(ctx, &varied, requestOptions, edge.protocol)
+		result.variations = append(result.variations, internal.CompareVariation(result.baseline, v, response))
+	}
+
+	return result
+}
+
+var (
+	varyCommand = &cobra.Command{
+		Use:   "vary",
+		Short: "Exec `gostat vary https://domain.com -t domain.com`",
+		Long:  "Sends the URL to each A record of the target domain with systematically varied Accept-Encoding, Accept-Language, User-Agent classes, cookies and query strings, and reports which variations produce a distinct cached object.",
+		PreRun: func(cmd *cobra.Command, args []string) {
+			bindRequestFlags(cmd)
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			ctx := cmd.Context()
+			edge, err := parseEdgeArgs(cmd, args)
+			if err != nil {
+				panicRed(err)
+			}
+			edge.requestOptions.FullBody = true
+			edge.requestOptions.RawEncoding = true
+			if edge.requestOptions.Header == nil {
+				edge.requestOptions.Header = http.Header{}
+			}
+			if edge.requestOptions.Header.Get("Accept-Encoding") == "" {
+				edge.requestOptions.Header.Set("Accept-Encoding", internal.BaselineEncoding)
+			}
+
+			variations := internal.DefaultVariations(edge.addrInfo.Url)
+			ips := internal.SortIPs(edge.ips)
+			results := make([]varyResult, len(ips))
+			forEachEdge(ips, edge.addrInfo, viper.GetInt("concurrency-count"), func(i int, addr *internal.Address) {
+				results[i] = probeVariations(ctx, addr, edge, variations)
+			})
+
+			for i, r := range results {
+				internal.PrintVariations(edge.rawURL, ips[i], r.baseline, r.variations)
+			}
+		},
+	}
+)
+
+func init() {
+	addRequestFlags(varyCommand)
+
+	rootCmd.AddCommand(varyCommand)
+}
@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"os"
+	"time"
+
+	"github.com/ghdwlsgur/gostat/internal"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var (
+	warmCommand = &cobra.Command{
+		Use:   "warm",
+		Short: "Exec `gostat warm https://domain.com -t domain.com`",
+		Long:  "Repeatedly requests the URL from each A record of the target domain until the cache status of every edge becomes HIT, and reports how many requests and how long it took per edge with the progression of Age.",
+		PreRun: func(cmd *cobra.Command, args []string) {
+			bindRequestFlags(cmd)
+			viper.BindPFlag("max-attempts", cmd.Flags().Lookup("max-attempts"))
+			viper.BindPFlag("interval", cmd.Flags().Lookup("interval"))
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			ctx := cmd.Context()
+			edge, err := parseEdgeArgs(cmd, args)
+			if err != nil {
+				panicRed(err)
+			}
+			edge.requestOptions.FullBody = true
+
+			maxAttempts := viper.GetInt("max-attempts")
+			interval := viper.GetDuration("interval")
+
+			ips := internal.SortIPs(edge.ips)
+			results := make([]internal.WarmResult, len(ips))
+			forEachEdge(ips, edge.addrInfo, viper.GetInt("concurrency-count"), func(i int, addr *internal.Address) {
+				results[i] = internal.WarmEdge(ctx, addr.IP, func() *internal.Response {
+					return fetchEdge(ctx, addr, edge.requestOptions, edge.protocol)
+				}, maxAttempts, interval)
+			})
+
+			internal.PrintWarmResults(edge.rawURL, results)
+			for _, r := range results {
+				if !r.Warm {
+					os.Exit(1)
+				}
+			}
+		},
+	}
+)
+
+func init() {
+	addRequestFlags(warmCommand)
+	warmCommand.Flags().Int("max-attempts", 10, "[optional] Maximum number of requests per edge.")
+	warmCommand.Flags().Duration("interval", time.Second, "[optional] Time to wait between requests to the same edge.")
+
+	rootCmd.AddCommand(warmCommand)
+}
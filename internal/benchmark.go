@@ -0,0 +1,297 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+// Options of a benchmark. The benchmark stops once Requests requests were sent or Duration
+// elapsed, whichever comes first, and runs until the context is done when both are zero.
+type BenchmarkOptions struct {
+	// Requests per second over every edge, unlimited when zero.
+	Rate        float64
+	Duration    time.Duration
+	Requests    int
+	Concurrency int
+}
+
+// A token bucket that lets rate requests per second through, with bursts of up to burst requests.
+type tokenBucket struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(rate, burst float64) *tokenBucket {
+	return &tokenBucket{rate: rate, burst: burst, tokens: burst, last: time.Now()}
+}
+
+// Takes a token, waiting for it when the bucket is empty, and reports whether the context
+// was still running by then. A nil bucket never waits.
+func (b *tokenBucket) take(ctx context.Context) bool {
+	if b == nil {
+		return ctx.Err() == nil
+	}
+
+	b.mu.Lock()
+	now := time.Now()
+	b.tokens = math.Min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+	// The token is reserved right away, so the bucket goes negative while callers wait.
+	b.tokens--
+	wait := time.Duration(-b.tokens / b.rate * float64(time.Second))
+	b.mu.Unlock()
+
+	if wait <= 0 {
+		return ctx.Err() == nil
+	}
+	return sleep(ctx, wait)
+}
+
+// Statistics of the responses of an edge, or of every edge, during a benchmark.
+type BenchmarkStats struct {
+	EdgeIP   string
+	Requests int
+	// Number of responses by status code, or by category of the error.
+	Statuses  map[string]int
+	Latencies []time.Duration
+}
+
+func newBenchmarkStats(edgeIP string) *BenchmarkStats {
+	return &BenchmarkStats{EdgeIP: edgeIP, Statuses: map[string]int{}}
+}
+
+func (s *BenchmarkStats) add(response *Response, latency time.Duration) {
+	s.Requests++
+	s.Statuses[response.GetStatusCode()]++
+	s.Latencies = append(s.Latencies, latency)
+}
+
+// Returns the latency below which the fraction p of the requests completed, with p between 0 and 1.
+func (s *BenchmarkStats) Percentile(p float64) time.Duration {
+	if len(s.Latencies) == 0 {
+		return 0
+	}
+
+	sorted := make([]time.Duration, len(s.Latencies))
+	copy(sorted, s.Latencies)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	i := int(math.Ceil(p*float64(len(sorted)))) - 1
+	if i < 0 {
+		i = 0
+	}
+	return sorted[i]
+}
+
+// Returns the latency of every request on average.
+func (s *BenchmarkStats) Mean() time.Duration {
+	if len(s.Latencies) == 0 {
+		return 0
+	}
+
+	var total time.Duration
+	for _, latency := range s.Latencies {
+		total += latency
+	}
+	return total / time.Duration(len(s.Latencies))
+}
+
+// Summary of a benchmark, with the statistics of every edge and of the edges together.
+type BenchmarkSummary struct {
+	URL     string
+	Elapsed time.Duration
+	Total   *BenchmarkStats
+	Edges   []*BenchmarkStats
+}
+
+// Returns the number of responses per second.
+func (s *BenchmarkSummary) Throughput() float64 {
+	if s.Elapsed <= 0 {
+		return 0
+	}
+	return float64(s.Total.Requests) / s.Elapsed.Seconds()
+}
+
+// Benchmark sends requests to the edges in turn from Concurrency goroutines, at the rate
+// and for as long as the options allow.
+type Benchmark struct {
+	options BenchmarkOptions
+	edges   []string
+	request func(ctx context.Context, edgeIP string) *Response
+	sent    int64
+}
+
+// Returns a benchmark of the edges, where request sends a single request to an edge.
+// There must be at least one edge, a target with only IPv6 addresses has none.
+func NewBenchmark(options BenchmarkOptions, edges []string, request func(ctx context.Context, edgeIP string) *Response) (*Benchmark, error) {
+	if len(edges) == 0 {
+		return nil, fmt.Errorf("no IPv4 edge to benchmark")
+	}
+	return &Benchmark{options: options, edges: edges, request: request}, nil
+}
+
+// Returns the number of requests sent so far.
+func (b *Benchmark) Sent() int64 {
+	return atomic.LoadInt64(&b.sent)
+}
+
+// Runs the benchmark and returns the statistics of the responses received before it stopped.
+// The requests still in flight when it stops are not counted.
+func (b *Benchmark) Run(ctx context.Context, url string) *BenchmarkSummary {
+	if b.options.Duration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, b.options.Duration)
+		defer cancel()
+	}
+
+	var bucket *tokenBucket
+	if b.options.Rate > 0 {
+		bucket = newTokenBucket(b.options.Rate, 1)
+	}
+
+	concurrency := b.options.Concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	summary := &BenchmarkSummary{URL: url, Total: newBenchmarkStats("")}
+	stats := make(map[string]*BenchmarkStats, len(b.edges))
+	for _, edge := range b.edges {
+		stats[edge] = newBenchmarkStats(edge)
+		summary.Edges = append(summary.Edges, stats[edge])
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	start := time.Now()
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for bucket.take(ctx) {
+				n := atomic.AddInt64(&b.sent, 1)
+				if b.options.Requests > 0 && n > int64(b.options.Requests) {
+					atomic.AddInt64(&b.sent, -1)
+					return
+				}
+
+				edge := b.edges[(n-1)%int64(len(b.edges))]
+				requested := time.Now()
+				response := b.request(ctx, edge)
+				latency := time.Since(requested)
+				if ctx.Err() != nil {
+					return
+				}
+
+				mu.Lock()
+				stats[edge].add(response, latency)
+				summary.Total.add(response, latency)
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	summary.Elapsed = time.Since(start)
+
+	return summary
+}
+
+// Returns the number of responses by status, ordered by status.
+func formatStatuses(statuses map[string]int) string {
+	keys := make([]string, 0, len(statuses))
+	for status := range statuses {
+		keys = append(keys, status)
+	}
+	sort.Strings(keys)
+
+	values := make([]string, len(keys))
+	for i, status := range keys {
+		values[i] = fmt.Sprintf("%s: %d", status, statuses[status])
+	}
+	return strings.Join(values, ", ")
+}
+
+func printBenchmarkStats(s *BenchmarkStats) {
+	PrintFunc("Requests", fmt.Sprintf("%d", s.Requests))
+	PrintFunc("Status", formatStatuses(s.Statuses))
+	PrintFunc("Latency", fmt.Sprintf("mean %s, p50 %s, p90 %s, p99 %s, max %s",
+		s.Mean().Round(time.Microsecond),
+		s.Percentile(0.5).Round(time.Microsecond),
+		s.Percentile(0.9).Round(time.Microsecond),
+		s.Percentile(0.99).Round(time.Microsecond),
+		s.Percentile(1).Round(time.Microsecond),
+	))
+}
+
+// Prints the summary of the benchmark, followed by the statistics of each edge.
+func PrintBenchmark(summary *BenchmarkSummary) {
+	fmt.Printf("\n%s %s\n\n", color.HiWhiteString("Benchmark of"), color.HiYellowString(summary.URL))
+	PrintFunc("Duration", summary.Elapsed.Round(time.Millisecond).String())
+	PrintFunc("Throughput", color.HiMagentaString("%.1f req/s", summary.Throughput()))
+	printBenchmarkStats(summary.Total)
+
+	for _, edge := range summary.Edges {
+		fmt.Printf("\n[%s]\n", color.HiYellowString(edge.EdgeIP))
+		printBenchmarkStats(edge)
+	}
+	fmt.Println()
+}
+
+// Structure with the statistics of a benchmark written in json output.
+type BenchmarkStatsReport struct {
+	EdgeIP   string         `json:"edge,omitempty"`
+	Requests int            `json:"requests"`
+	Statuses map[string]int `json:"statuses"`
+	Mean     float64        `json:"mean_ms"`
+	P50      float64        `json:"p50_ms"`
+	P90      float64        `json:"p90_ms"`
+	P99      float64        `json:"p99_ms"`
+	Max      float64        `json:"max_ms"`
+}
+
+// Structure with the summary of a benchmark written in json output.
+type BenchmarkReport struct {
+	URL        string                 `json:"url"`
+	Elapsed    float64                `json:"elapsed_ms"`
+	Throughput float64                `json:"throughput_rps"`
+	Total      BenchmarkStatsReport   `json:"total"`
+	Edges      []BenchmarkStatsReport `json:"edges"`
+}
+
+func (s *BenchmarkStats) report() BenchmarkStatsReport {
+	return BenchmarkStatsReport{
+		EdgeIP:   s.EdgeIP,
+		Requests: s.Requests,
+		Statuses: s.Statuses,
+		Mean:     milliseconds(s.Mean()),
+		P50:      milliseconds(s.Percentile(0.5)),
+		P90:      milliseconds(s.Percentile(0.9)),
+		P99:      milliseconds(s.Percentile(0.99)),
+		Max:      milliseconds(s.Percentile(1)),
+	}
+}
+
+// Returns the summary as a structure that can be written in json output.
+func (s *BenchmarkSummary) Report() BenchmarkReport {
+	report := BenchmarkReport{
+		URL:        s.URL,
+		Elapsed:    milliseconds(s.Elapsed),
+		Throughput: s.Throughput(),
+		Total:      s.Total.report(),
+	}
+	for _, edge := range s.Edges {
+		report.Edges = append(report.Edges, edge.report())
+	}
+	return report
+}
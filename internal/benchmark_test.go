@@ -0,0 +1,77 @@
+package internal
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// Testing the rate, the request limit and the distribution of the requests over the edges.
+func TestBenchmark(t *testing.T) {
+	edges := []string{"1.1.1.1", "1.1.1.2"}
+	request := func(ctx context.Context, edgeIP string) *Response {
+		if edgeIP == "1.1.1.2" {
+			return &Response{StatusCode: 503}
+		}
+		return &Response{StatusCode: 200}
+	}
+
+	benchmark, err := NewBenchmark(BenchmarkOptions{Rate: 100, Requests: 20, Concurrency: 4}, edges, request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	summary := benchmark.Run(context.Background(), "https://example.com/")
+	if elapsed := time.Since(start); elapsed < 150*time.Millisecond {
+		t.Errorf("expected 20 requests at 100 req/s to take about 190ms, took %s", elapsed)
+	}
+	if summary.Total.Requests != 20 || summary.Edges[0].Requests != 10 || summary.Edges[1].Requests != 10 {
+		t.Fatalf("expected 10 requests per edge, got %d and %d", summary.Edges[0].Requests, summary.Edges[1].Requests)
+	}
+	if summary.Total.Statuses["200"] != 10 || summary.Total.Statuses["503"] != 10 {
+		t.Errorf("unexpected statuses: %v", summary.Total.Statuses)
+	}
+
+	benchmark, err = NewBenchmark(BenchmarkOptions{Duration: 100 * time.Millisecond, Concurrency: 2}, edges, func(ctx context.Context, edgeIP string) *Response {
+		time.Sleep(10 * time.Millisecond)
+		return &Response{StatusCode: 200}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	summary = benchmark.Run(context.Background(), "https://example.com/")
+	if summary.Elapsed > 200*time.Millisecond || summary.Total.Requests == 0 {
+		t.Errorf("expected the benchmark to stop after 100ms, ran %s with %d requests", summary.Elapsed, summary.Total.Requests)
+	}
+}
+
+// Testing that a benchmark needs at least one edge.
+func TestBenchmarkWithoutEdges(t *testing.T) {
+	benchmark, err := NewBenchmark(BenchmarkOptions{Requests: 1}, nil, func(ctx context.Context, edgeIP string) *Response {
+		return &Response{StatusCode: 200}
+	})
+	if err == nil || benchmark != nil {
+		t.Errorf("expected an error without edges, got %v", benchmark)
+	}
+}
+
+// Testing the percentiles of the latencies.
+func TestBenchmarkPercentile(t *testing.T) {
+	stats := newBenchmarkStats("")
+	for i := 100; i >= 1; i-- {
+		stats.add(&Response{StatusCode: 200}, time.Duration(i)*time.Millisecond)
+	}
+
+	if p := stats.Percentile(0.5); p != 50*time.Millisecond {
+		t.Errorf("expected p50 of 50ms, got %s", p)
+	}
+	if p := stats.Percentile(0.99); p != 99*time.Millisecond {
+		t.Errorf("expected p99 of 99ms, got %s", p)
+	}
+	if p := stats.Percentile(1); p != 100*time.Millisecond {
+		t.Errorf("expected max of 100ms, got %s", p)
+	}
+	if mean := stats.Mean(); mean != 50500*time.Microsecond {
+		t.Errorf("expected a mean of 50.5ms, got %s", mean)
+	}
+}
@@ -0,0 +1,323 @@
+package internal
+
+import (
+	"bytes"
+	"fmt"
+	"mime"
+	"regexp"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+const (
+	// Number of unchanged lines shown around each change of a unified diff.
+	diffContext = 3
+	// Above this many line pairs the bodies are reported as entirely replaced.
+	maxDiffCells = 4000000
+)
+
+// Structure with the difference between the body of an outlier edge and the majority body.
+type BodyDiff struct {
+	EdgeIP       string
+	MajorityIP   string
+	Text         bool
+	Diff         string
+	Offset       int
+	MajoritySize int64
+	EdgeSize     int64
+	Truncated    bool
+	Ignored      bool
+}
+
+// Reports whether the content type has a text representation worth diffing line by line.
+func IsTextContent(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+
+	switch {
+	case strings.HasPrefix(mediaType, "text/"),
+		strings.HasSuffix(mediaType, "+json"),
+		strings.HasSuffix(mediaType, "+xml"):
+		return true
+	}
+
+	switch mediaType {
+	case "application/json", "application/xml", "application/javascript",
+		"application/x-javascript", "application/ecmascript",
+		"application/x-www-form-urlencoded", "image/svg+xml":
+		return true
+	}
+	return false
+}
+
+// Compiles the patterns whose matches are ignored when diffing bodies.
+func CompileIgnorePatterns(patterns []string) ([]*regexp.Regexp, error) {
+	var compiled []*regexp.Regexp
+	for _, p := range patterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ignore pattern %q: %w", p, err)
+		}
+		compiled = append(compiled, re)
+	}
+	return compiled, nil
+}
+
+func normalizeBody(body []byte, ignore []*regexp.Regexp) []byte {
+	for _, re := range ignore {
+		body = re.ReplaceAll(body, []byte("<ignored>"))
+	}
+	return body
+}
+
+// Returns the offset of the first differing byte, or -1 when both are equal.
+func FirstDifference(a, b []byte) int {
+	n := len(a)
+	if len(b) < n {
+		n = len(b)
+	}
+	for i := 0; i < n; i++ {
+		if a[i] != b[i] {
+			return i
+		}
+	}
+	if len(a) != len(b) {
+		return n
+	}
+	return -1
+}
+
+// Diffs the body of every edge whose hash differs from the majority against the majority body.
+func DiffBodies(responses []*Response, ignore []*regexp.Regexp) []BodyDiff {
+	var succeeded []*Response
+	for _, r := range responses {
+		if r.Error == nil {
+			succeeded = append(succeeded, r)
+		}
+	}
+
+	hashes := groupByField(succeeded, "Hash")
+	if hashes.Consistent() {
+		return nil
+	}
+
+	var majority *Response
+	for _, r := range succeeded {
+		if r.GetHash() == hashes.majority() {
+			majority = r
+			break
+		}
+	}
+
+	var diffs []BodyDiff
+	for _, r := range succeeded {
+		if r.GetHash() == hashes.majority() {
+			continue
+		}
+
+		diff := BodyDiff{
+			EdgeIP:       r.EdgeIP,
+			MajorityIP:   majority.EdgeIP,
+			Text:         IsTextContent(majority.ContentType) && IsTextContent(r.ContentType),
+			MajoritySize: majority.BodySize,
+			EdgeSize:     r.BodySize,
+			Truncated:    int64(len(majority.Body)) < majority.BodySize || int64(len(r.Body)) < r.BodySize,
+		}
+
+		if diff.Text {
+			a, b := normalizeBody(majority.Body, ignore), normalizeBody(r.Body, ignore)
+			if bytes.Equal(a, b) {
+				diff.Ignored = true
+			} else {
+				diff.Diff = UnifiedDiff(string(a), string(b), majority.EdgeIP, r.EdgeIP)
+			}
+		} else {
+			diff.Offset = FirstDifference(majority.Body, r.Body)
+		}
+		diffs = append(diffs, diff)
+	}
+
+	return diffs
+}
+
+type diffOp struct {
+	kind byte
+	line string
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
+
+// Returns the edit script from a to b based on their longest common subsequence.
+func diffLines(a, b []string) []diffOp {
+	var prefix, suffix []diffOp
+	for len(a) > 0 && len(b) > 0 && a[0] == b[0] {
+		prefix = append(prefix, diffOp{' ', a[0]})
+		a, b = a[1:], b[1:]
+	}
+	for len(a) > 0 && len(b) > 0 && a[len(a)-1] == b[len(b)-1] {
+		suffix = append([]diffOp{{' ', a[len(a)-1]}}, suffix...)
+		a, b = a[:len(a)-1], b[:len(b)-1]
+	}
+
+	ops := prefix
+	if len(a)*len(b) > maxDiffCells {
+		for _, line := range a {
+			ops = append(ops, diffOp{'-', line})
+		}
+		for _, line := range b {
+			ops = append(ops, diffOp{'+', line})
+		}
+		return append(ops, suffix...)
+	}
+
+	lcs := make([][]int32, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int32, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			ops = append(ops, diffOp{' ', a[i]})
+			i, j = i+1, j+1
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			ops = append(ops, diffOp{'-', a[i]})
+			i++
+		default:
+			ops = append(ops, diffOp{'+', b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		ops = append(ops, diffOp{'-', a[i]})
+	}
+	for ; j < len(b); j++ {
+		ops = append(ops, diffOp{'+', b[j]})
+	}
+
+	return append(ops, suffix...)
+}
+
+// Returns the unified diff between two texts with three lines of context.
+func UnifiedDiff(a, b, nameA, nameB string) string {
+	ops := diffLines(splitLines(a), splitLines(b))
+
+	// line numbers of a and b before each op
+	lineA, lineB := make([]int, len(ops)+1), make([]int, len(ops)+1)
+	for k, op := range ops {
+		lineA[k+1], lineB[k+1] = lineA[k], lineB[k]
+		if op.kind != '+' {
+			lineA[k+1]++
+		}
+		if op.kind != '-' {
+			lineB[k+1]++
+		}
+	}
+
+	var buf strings.Builder
+	fmt.Fprintf(&buf, "--- %s\n+++ %s\n", nameA, nameB)
+
+	for k := 0; k < len(ops); {
+		for k < len(ops) && ops[k].kind == ' ' {
+			k++
+		}
+		if k == len(ops) {
+			break
+		}
+
+		start := k - diffContext
+		if start < 0 {
+			start = 0
+		}
+
+		end := k
+		for end < len(ops) {
+			if ops[end].kind != ' ' {
+				end++
+				continue
+			}
+			run := end
+			for run < len(ops) && ops[run].kind == ' ' {
+				run++
+			}
+			if run == len(ops) || run-end > 2*diffContext {
+				end += diffContext
+				if end > len(ops) {
+					end = len(ops)
+				}
+				break
+			}
+			end = run
+		}
+
+		fmt.Fprintf(&buf, "@@ -%d,%d +%d,%d @@\n",
+			lineA[start]+1, lineA[end]-lineA[start], lineB[start]+1, lineB[end]-lineB[start])
+		for _, op := range ops[start:end] {
+			fmt.Fprintf(&buf, "%c%s\n", op.kind, op.line)
+		}
+		k = end
+	}
+
+	return buf.String()
+}
+
+// Prints the body difference of each outlier edge against the majority body.
+func PrintBodyDiffs(diffs []BodyDiff) {
+	if len(diffs) == 0 {
+		return
+	}
+
+	fmt.Printf("%s\n", color.HiWhiteString("Body Differences"))
+	for _, d := range diffs {
+		fmt.Printf("\n[%s] - [%s]\n", color.HiYellowString(d.MajorityIP), color.HiYellowString(d.EdgeIP))
+		PrintFunc("Size", fmt.Sprintf("%d -> %d", d.MajoritySize, d.EdgeSize))
+		if d.Truncated {
+			PrintFunc("Truncated", color.HiYellowString("only the kept part of the bodies is compared"))
+		}
+
+		switch {
+		case d.Ignored:
+			PrintFunc("Result", color.HiGreenString("identical after ignoring patterns"))
+		case d.Text:
+			for _, line := range splitLines(d.Diff) {
+				switch {
+				case strings.HasPrefix(line, "+++"), strings.HasPrefix(line, "---"):
+					fmt.Println(color.HiWhiteString(line))
+				case strings.HasPrefix(line, "@@"):
+					fmt.Println(color.HiCyanString(line))
+				case strings.HasPrefix(line, "+"):
+					fmt.Println(color.HiGreenString(line))
+				case strings.HasPrefix(line, "-"):
+					fmt.Println(color.HiRedString(line))
+				default:
+					fmt.Println(line)
+				}
+			}
+		case d.Offset < 0:
+			PrintFunc("Offset", "no difference in the kept part of the bodies")
+		default:
+			PrintFunc("Offset", fmt.Sprintf("first differing byte at %d", d.Offset))
+		}
+	}
+	fmt.Println()
+}
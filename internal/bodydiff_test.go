@@ -0,0 +1,51 @@
+package internal
+
+import (
+	"regexp"
+	"testing"
+)
+
+// Testing the unified diff of two texts.
+func TestUnifiedDiff(t *testing.T) {
+	a := "one\ntwo\nthree\nfour\nfive\nsix\nseven\neight\nnine\n"
+	b := "one\ntwo\nthree\nfour\nFIVE\nsix\nseven\neight\nnine\nten\n"
+
+	expected := `--- a
++++ b
+@@ -2,8 +2,9 @@
+ two
+ three
+ four
+-five
++FIVE
+ six
+ seven
+ eight
+ nine
++ten
+`
+	if diff := UnifiedDiff(a, b, "a", "b"); diff != expected {
+		t.Errorf("unexpected diff:\n%s", diff)
+	}
+}
+
+// Testing the body difference of text and binary outliers.
+func TestDiffBodies(t *testing.T) {
+	ignore := []*regexp.Regexp{regexp.MustCompile(`nonce="[^"]*"`)}
+	responses := []*Response{
+		{EdgeIP: "1.1.1.1", ContentType: "text/html; charset=utf-8", Body: []byte(`<p nonce="a">hi</p>`), Hash: []byte("a")},
+		{EdgeIP: "1.1.1.2", ContentType: "text/html; charset=utf-8", Body: []byte(`<p nonce="a">hi</p>`), Hash: []byte("a")},
+		{EdgeIP: "1.1.1.3", ContentType: "text/html; charset=utf-8", Body: []byte(`<p nonce="b">hi</p>`), Hash: []byte("b")},
+	}
+
+	diffs := DiffBodies(responses, ignore)
+	if len(diffs) != 1 || !diffs[0].Ignored {
+		t.Errorf("expected the nonce to be ignored: %+v", diffs)
+	}
+
+	responses[2].ContentType = "image/png"
+	diffs = DiffBodies(responses, nil)
+	if len(diffs) != 1 || diffs[0].Text || diffs[0].Offset != 10 {
+		t.Errorf("expected a binary difference at offset 10: %+v", diffs)
+	}
+}
@@ -0,0 +1,194 @@
+package internal
+
+import (
+	"net/http"
+	"strings"
+)
+
+// Cache status normalized across CDN vendors.
+type CacheStatus string
+
+const (
+	CacheHit     CacheStatus = "HIT"
+	CacheMiss    CacheStatus = "MISS"
+	CacheStale   CacheStatus = "STALE"
+	CacheBypass  CacheStatus = "BYPASS"
+	CacheExpired CacheStatus = "EXPIRED"
+	CacheUnknown CacheStatus = ""
+)
+
+// Structure with the cache status decoded from the response headers of a CDN vendor.
+type CacheInfo struct {
+	Vendor string      `json:"vendor"`
+	Status CacheStatus `json:"status"`
+	PoP    string      `json:"pop"`
+}
+
+func (c CacheInfo) String() string {
+	if c.Status == CacheUnknown {
+		return "-"
+	}
+	if c.PoP == "" {
+		return string(c.Status)
+	}
+	return string(c.Status) + " (" + c.PoP + ")"
+}
+
+// A decoder recognizes the cache headers of a CDN vendor, it returns false when the headers are not its own.
+type CacheDecoder interface {
+	Decode(header http.Header) (CacheInfo, bool)
+}
+
+// An adapter to use an ordinary function as a CacheDecoder.
+type CacheDecoderFunc func(header http.Header) (CacheInfo, bool)
+
+func (f CacheDecoderFunc) Decode(header http.Header) (CacheInfo, bool) {
+	return f(header)
+}
+
+var cacheDecoders = []CacheDecoder{
+	CacheDecoderFunc(decodeCloudflare),
+	CacheDecoderFunc(decodeAkamai),
+	CacheDecoderFunc(decodeCloudFront),
+	CacheDecoderFunc(decodeFastly),
+	CacheDecoderFunc(decodeCacheStatusField),
+	CacheDecoderFunc(decodeXCache),
+}
+
+// Registers a decoder that is tried before the built-in ones.
+func RegisterCacheDecoder(decoder CacheDecoder) {
+	cacheDecoders = append([]CacheDecoder{decoder}, cacheDecoders...)
+}
+
+// Returns the cache status of the first decoder recognizing the headers.
+func DecodeCacheStatus(header http.Header) CacheInfo {
+	for _, decoder := range cacheDecoders {
+		if info, ok := decoder.Decode(header); ok {
+			return info
+		}
+	}
+	return CacheInfo{}
+}
+
+// Maps a vendor specific cache value such as TCP_REFRESH_HIT or "Hit from cloudfront" to a normalized status.
+func normalizeCacheValue(value string) CacheStatus {
+	v := strings.ToUpper(value)
+	switch {
+	case strings.Contains(v, "BYPASS"), strings.Contains(v, "DYNAMIC"), strings.Contains(v, "PASS"):
+		return CacheBypass
+	case strings.Contains(v, "STALE"), strings.Contains(v, "UPDATING"):
+		return CacheStale
+	case strings.Contains(v, "EXPIRED"), strings.Contains(v, "REFRESH"):
+		return CacheExpired
+	case strings.Contains(v, "HIT"), strings.Contains(v, "REVALIDATED"):
+		return CacheHit
+	case strings.Contains(v, "MISS"):
+		return CacheMiss
+	}
+	return CacheUnknown
+}
+
+// Returns the last member of a comma separated header value, which is the cache closest to the client.
+func lastMember(value string) string {
+	members := strings.Split(value, ",")
+	return strings.TrimSpace(members[len(members)-1])
+}
+
+// CF-Cache-Status: HIT, CF-Ray: 7d1f2c3a4b5c6d7e-ICN
+func decodeCloudflare(header http.Header) (CacheInfo, bool) {
+	status := header.Get("CF-Cache-Status")
+	if status == "" {
+		return CacheInfo{}, false
+	}
+
+	info := CacheInfo{Vendor: "Cloudflare", Status: normalizeCacheValue(status)}
+	if ray := header.Get("CF-Ray"); strings.Contains(ray, "-") {
+		info.PoP = ray[strings.LastIndex(ray, "-")+1:]
+	}
+	return info, true
+}
+
+// Akamai-Cache-Status: Hit from child, X-Cache: TCP_HIT from a23-45-67-89.deploy.akamaitechnologies.com
+func decodeAkamai(header http.Header) (CacheInfo, bool) {
+	status := header.Get("Akamai-Cache-Status")
+	xcache := header.Get("X-Cache")
+	if status == "" && !strings.Contains(xcache, "akamai") {
+		return CacheInfo{}, false
+	}
+
+	info := CacheInfo{Vendor: "Akamai"}
+	if status != "" {
+		info.Status = normalizeCacheValue(strings.Split(status, ",")[0])
+	} else {
+		info.Status = normalizeCacheValue(strings.Fields(xcache)[0])
+	}
+	if fields := strings.Fields(xcache); len(fields) == 3 && fields[1] == "from" {
+		info.PoP = fields[2]
+	}
+	return info, true
+}
+
+// X-Cache: Hit from cloudfront, X-Amz-Cf-Pop: ICN54-C1
+func decodeCloudFront(header http.Header) (CacheInfo, bool) {
+	pop := header.Get("X-Amz-Cf-Pop")
+	xcache := header.Get("X-Cache")
+	if pop == "" && !strings.Contains(strings.ToLower(xcache), "cloudfront") {
+		return CacheInfo{}, false
+	}
+
+	info := CacheInfo{Vendor: "CloudFront", Status: normalizeCacheValue(xcache), PoP: pop}
+	if strings.HasPrefix(strings.ToLower(xcache), "refreshhit") {
+		info.Status = CacheExpired
+	}
+	return info, true
+}
+
+// X-Cache: MISS, HIT, X-Served-By: cache-iad-kiad7000025-IAD, cache-nrt-rjtf7700031-NRT
+func decodeFastly(header http.Header) (CacheInfo, bool) {
+	servedBy := header.Get("X-Served-By")
+	if !strings.HasPrefix(servedBy, "cache-") {
+		return CacheInfo{}, false
+	}
+
+	info := CacheInfo{Vendor: "Fastly", Status: normalizeCacheValue(lastMember(header.Get("X-Cache")))}
+	if node := lastMember(servedBy); strings.Contains(node, "-") {
+		info.PoP = node[strings.LastIndex(node, "-")+1:]
+	}
+	return info, true
+}
+
+// Cache-Status: OriginCache; hit, ExampleCDN; fwd=uri-miss; stored (RFC 9211)
+func decodeCacheStatusField(header http.Header) (CacheInfo, bool) {
+	value := header.Get("Cache-Status")
+	if value == "" {
+		return CacheInfo{}, false
+	}
+
+	params := strings.Split(lastMember(value), ";")
+	info := CacheInfo{Vendor: strings.Trim(strings.TrimSpace(params[0]), `"`)}
+	for _, p := range params[1:] {
+		p = strings.TrimSpace(p)
+		switch {
+		case p == "hit":
+			info.Status = CacheHit
+		case p == "fwd=bypass" || p == "fwd=method" || p == "fwd=request":
+			info.Status = CacheBypass
+		case p == "fwd=stale":
+			info.Status = CacheExpired
+		case strings.HasPrefix(p, "fwd="):
+			info.Status = CacheMiss
+		}
+	}
+	return info, info.Status != CacheUnknown
+}
+
+// X-Cache: HIT, used by Varnish, Nginx and most other caches.
+func decodeXCache(header http.Header) (CacheInfo, bool) {
+	value := header.Get("X-Cache")
+	if value == "" {
+		return CacheInfo{}, false
+	}
+
+	info := CacheInfo{Status: normalizeCacheValue(lastMember(value))}
+	return info, info.Status != CacheUnknown
+}
@@ -0,0 +1,53 @@
+package internal
+
+import (
+	"net/http"
+	"testing"
+)
+
+// Testing the cache status decoded from the headers of each vendor.
+func TestDecodeCacheStatus(t *testing.T) {
+	tests := []struct {
+		header   map[string]string
+		expected CacheInfo
+	}{
+		{
+			map[string]string{"CF-Cache-Status": "DYNAMIC", "CF-Ray": "7d1f2c3a4b5c6d7e-ICN"},
+			CacheInfo{Vendor: "Cloudflare", Status: CacheBypass, PoP: "ICN"},
+		},
+		{
+			map[string]string{"Akamai-Cache-Status": "Miss from child, Hit from parent", "X-Cache": "TCP_MISS from a23-45-67-89.deploy.akamaitechnologies.com"},
+			CacheInfo{Vendor: "Akamai", Status: CacheMiss, PoP: "a23-45-67-89.deploy.akamaitechnologies.com"},
+		},
+		{
+			map[string]string{"X-Cache": "RefreshHit from cloudfront", "X-Amz-Cf-Pop": "ICN54-C1"},
+			CacheInfo{Vendor: "CloudFront", Status: CacheExpired, PoP: "ICN54-C1"},
+		},
+		{
+			map[string]string{"X-Cache": "MISS, HIT", "X-Served-By": "cache-iad-kiad7000025-IAD, cache-nrt-rjtf7700031-NRT"},
+			CacheInfo{Vendor: "Fastly", Status: CacheHit, PoP: "NRT"},
+		},
+		{
+			map[string]string{"Cache-Status": "OriginCache; hit, ExampleCDN; fwd=stale; stored"},
+			CacheInfo{Vendor: "ExampleCDN", Status: CacheExpired},
+		},
+		{
+			map[string]string{"X-Cache": "STALE"},
+			CacheInfo{Status: CacheStale},
+		},
+		{
+			map[string]string{"Server": "nginx"},
+			CacheInfo{},
+		},
+	}
+
+	for _, test := range tests {
+		header := http.Header{}
+		for k, v := range test.header {
+			header.Set(k, v)
+		}
+		if info := DecodeCacheStatus(header); info != test.expected {
+			t.Errorf("%v: expected %+v, got %+v", test.header, test.expected, info)
+		}
+	}
+}
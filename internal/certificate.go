@@ -0,0 +1,6 @@
+package internal
+
+import "github.com/ghdwlsgur/gostat/pkg/gostat"
+
+// Structure with the negotiated TLS connection and the leaf certificate of the edge.
+type TLSInfo = gostat.TLSInfo
@@ -0,0 +1,209 @@
+package internal
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/tcnksm/go-httpstat"
+)
+
+// Latency steps that can be asserted, named as in the json output.
+var LatencyPhases = map[string]func(r *httpstat.Result) time.Duration{
+	"dns_lookup":        func(r *httpstat.Result) time.Duration { return r.DNSLookup },
+	"tcp_connection":    func(r *httpstat.Result) time.Duration { return r.TCPConnection },
+	"tls_handshake":     func(r *httpstat.Result) time.Duration { return r.TLSHandshake },
+	"server_processing": func(r *httpstat.Result) time.Duration { return r.ServerProcessing },
+	"total": func(r *httpstat.Result) time.Duration {
+		return r.DNSLookup + r.TCPConnection + r.TLSHandshake + r.ServerProcessing
+	},
+}
+
+// Assertions checked against every edge of a url.
+type CheckSpec struct {
+	URL              string                   `mapstructure:"url"`
+	Status           int                      `mapstructure:"status"`
+	Headers          map[string]string        `mapstructure:"headers"`
+	ForbiddenHeaders map[string]string        `mapstructure:"forbidden-headers"`
+	Latency          map[string]time.Duration `mapstructure:"latency"`
+	SameHash         bool                     `mapstructure:"same-hash"`
+	CertDays         int                      `mapstructure:"cert-days"`
+}
+
+// Checks that the regular expressions and latency steps of the spec are valid.
+func (s CheckSpec) Validate() error {
+	for _, headers := range []map[string]string{s.Headers, s.ForbiddenHeaders} {
+		for field, pattern := range headers {
+			if _, err := regexp.Compile(pattern); err != nil {
+				return fmt.Errorf("%s: invalid pattern for %s: %w", s.URL, field, err)
+			}
+		}
+	}
+	for phase := range s.Latency {
+		if _, ok := LatencyPhases[phase]; !ok {
+			return fmt.Errorf("%s: unknown latency step %s", s.URL, phase)
+		}
+	}
+	return nil
+}
+
+// Result of one assertion on one edge.
+type CheckResult struct {
+	URL     string
+	EdgeIP  string
+	Name    string
+	Passed  bool
+	Message string
+	Elapsed time.Duration
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Returns the failed result of a spec whose url could not be parsed or resolved to its edges,
+// so that it is reported with the others rather than aborting the run.
+func ResolveFailure(spec CheckSpec, err error) CheckResult {
+	return CheckResult{URL: spec.URL, EdgeIP: "-", Name: "resolve", Message: err.Error()}
+}
+
+// Runs every assertion of the spec against the responses of all edges of its url.
+func RunChecks(spec CheckSpec, responses []*Response) []CheckResult {
+	var results []CheckResult
+
+	var succeeded []*Response
+	for _, r := range responses {
+		if r.Error == nil {
+			succeeded = append(succeeded, r)
+		}
+	}
+	majority := groupByField(succeeded, "Hash").majority()
+
+	for _, r := range responses {
+		elapsed := LatencyPhases["total"](&r.Latency)
+		add := func(name string, passed bool, format string, args ...interface{}) {
+			results = append(results, CheckResult{
+				URL:     spec.URL,
+				EdgeIP:  r.EdgeIP,
+				Name:    name,
+				Passed:  passed,
+				Message: fmt.Sprintf(format, args...),
+				Elapsed: elapsed,
+			})
+		}
+
+		if r.Error != nil {
+			add("request", false, "%s", r.Error.Error())
+			continue
+		}
+		add("request", true, "%s", r.Status)
+
+		if spec.Status != 0 {
+			add("status", r.StatusCode == spec.Status, "expected %d, got %d", spec.Status, r.StatusCode)
+		}
+
+		for _, field := range sortedKeys(spec.Headers) {
+			name := "header " + http.CanonicalHeaderKey(field)
+			value, present := r.Header.Get(field), len(r.Header.Values(field)) > 0
+			switch {
+			case !present:
+				add(name, false, "missing, expected to match %q", spec.Headers[field])
+			default:
+				matched := regexp.MustCompile(spec.Headers[field]).MatchString(value)
+				add(name, matched, "%q, expected to match %q", value, spec.Headers[field])
+			}
+		}
+
+		for _, field := range sortedKeys(spec.ForbiddenHeaders) {
+			name := "forbidden header " + http.CanonicalHeaderKey(field)
+			value, present := r.Header.Get(field), len(r.Header.Values(field)) > 0
+			switch {
+			case !present:
+				add(name, true, "absent")
+			case spec.ForbiddenHeaders[field] == "":
+				add(name, false, "present with %q", value)
+			default:
+				matched := regexp.MustCompile(spec.ForbiddenHeaders[field]).MatchString(value)
+				add(name, !matched, "%q, expected not to match %q", value, spec.ForbiddenHeaders[field])
+			}
+		}
+
+		phases := make([]string, 0, len(spec.Latency))
+		for phase := range spec.Latency {
+			phases = append(phases, phase)
+		}
+		sort.Strings(phases)
+		for _, phase := range phases {
+			d := LatencyPhases[phase](&r.Latency)
+			add("latency "+phase, d <= spec.Latency[phase], "%s, expected at most %s", d.Round(time.Microsecond), spec.Latency[phase])
+		}
+
+		if spec.SameHash {
+			add("same hash", r.GetHash() == majority, "%s, majority of edges %s", r.GetHash(), majority)
+		}
+
+		if spec.CertDays > 0 {
+			switch {
+			case r.TLS == nil:
+				add("certificate", false, "no certificate presented")
+			case r.TLS.VerifyError != "":
+				add("certificate", false, "%s", r.TLS.VerifyError)
+			default:
+				add("certificate", r.TLS.DaysLeft() >= spec.CertDays, "expires in %d days, expected at least %d", r.TLS.DaysLeft(), spec.CertDays)
+			}
+		}
+	}
+
+	return results
+}
+
+// Prints the failed assertions grouped by url and edge followed by a summary.
+func PrintCheckResults(results []CheckResult) {
+	failed := 0
+	last := ""
+	for _, r := range results {
+		if r.Passed {
+			continue
+		}
+		failed++
+
+		if key := r.URL + " " + r.EdgeIP; key != last {
+			fmt.Printf("\n%s - [%s]\n", color.HiYellowString(r.URL), color.HiYellowString(r.EdgeIP))
+			last = key
+		}
+		fmt.Printf("\t%s %s: %s\n", color.HiRedString("FAIL"), r.Name, r.Message)
+	}
+
+	fmt.Println()
+	summary := fmt.Sprintf("%d assertions, %d passed, %d failed", len(results), len(results)-failed, failed)
+	if failed == 0 {
+		fmt.Println(color.HiGreenString(summary))
+	} else {
+		fmt.Println(color.HiRedString(summary))
+	}
+}
+
+// Reports whether every assertion passed.
+func ChecksPassed(results []CheckResult) bool {
+	for _, r := range results {
+		if !r.Passed {
+			return false
+		}
+	}
+	return true
+}
+
+// Parses "Name: pattern" into the header name and its pattern, the pattern may be empty.
+func ParseHeaderAssertion(value string) (string, string) {
+	field, pattern, _ := strings.Cut(value, ":")
+	return strings.TrimSpace(field), strings.TrimSpace(pattern)
+}
@@ -0,0 +1,62 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// Testing the assertions run against the responses of each edge.
+func TestRunChecks(t *testing.T) {
+	spec := CheckSpec{
+		URL:              "https://example.com/",
+		Status:           200,
+		Headers:          map[string]string{"Cache-Control": `max-age=\d+`},
+		ForbiddenHeaders: map[string]string{"Set-Cookie": ""},
+		Latency:          map[string]time.Duration{"total": time.Second},
+		SameHash:         true,
+	}
+	if err := spec.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	responses := []*Response{
+		{EdgeIP: "1.1.1.1", StatusCode: 200, Hash: []byte("a"), Header: http.Header{"Cache-Control": {"max-age=60"}}},
+		{EdgeIP: "1.1.1.2", StatusCode: 200, Hash: []byte("a"), Header: http.Header{"Cache-Control": {"no-store"}, "Set-Cookie": {"a=b"}}},
+		{EdgeIP: "1.1.1.3", Error: fmt.Errorf("connection refused")},
+	}
+
+	failed := map[string][]string{}
+	for _, r := range RunChecks(spec, responses) {
+		if !r.Passed {
+			failed[r.EdgeIP] = append(failed[r.EdgeIP], r.Name)
+		}
+	}
+
+	if len(failed["1.1.1.1"]) != 0 {
+		t.Errorf("expected every assertion to pass on 1.1.1.1: %v", failed["1.1.1.1"])
+	}
+	if fmt.Sprint(failed["1.1.1.2"]) != "[header Cache-Control forbidden header Set-Cookie]" {
+		t.Errorf("unexpected failures on 1.1.1.2: %v", failed["1.1.1.2"])
+	}
+	if fmt.Sprint(failed["1.1.1.3"]) != "[request]" {
+		t.Errorf("unexpected failures on 1.1.1.3: %v", failed["1.1.1.3"])
+	}
+
+	if err := (CheckSpec{Latency: map[string]time.Duration{"connect": time.Second}}).Validate(); err == nil {
+		t.Error("expected an unknown latency step to be rejected")
+	}
+}
+
+// Testing that a spec whose url could not be resolved fails without an edge.
+func TestResolveFailure(t *testing.T) {
+	result := ResolveFailure(CheckSpec{URL: "https://example.invalid/"}, errors.New("no such host"))
+	if result.Passed || result.URL != "https://example.invalid/" || result.Message != "no such host" {
+		t.Errorf("unexpected result: %+v", result)
+	}
+	if ChecksPassed([]CheckResult{{Passed: true}, result}) {
+		t.Error("expected the checks to fail")
+	}
+}
@@ -0,0 +1,189 @@
+package internal
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+// Fields compared across edges, in the order they are reported.
+var comparedFields = []string{"Status", "Hash", "ETag", "Last-Modified", "Content-Length", "Cache-Control"}
+
+// A group of edges that returned the same value for a field.
+type EdgeGroup struct {
+	Value string
+	Edges []string
+}
+
+// The values of a field grouped across edges, the first group is the majority.
+type FieldComparison struct {
+	Field  string
+	Groups []EdgeGroup
+}
+
+func (f FieldComparison) Consistent() bool {
+	return len(f.Groups) <= 1
+}
+
+func (f FieldComparison) majority() string {
+	if len(f.Groups) == 0 {
+		return ""
+	}
+	return f.Groups[0].Value
+}
+
+// Verdict of a single edge against the majority of edges.
+type EdgeVerdict struct {
+	EdgeIP    string
+	Stale     bool
+	Divergent []string
+	Error     error
+}
+
+func (v EdgeVerdict) Consistent() bool {
+	return v.Error == nil && !v.Stale && len(v.Divergent) == 0
+}
+
+// Structure with the result of comparing the responses of every edge.
+type Comparison struct {
+	Fields []FieldComparison
+	Edges  []EdgeVerdict
+}
+
+func (c *Comparison) Consistent() bool {
+	for _, v := range c.Edges {
+		if !v.Consistent() {
+			return false
+		}
+	}
+	return true
+}
+
+func fieldValue(r *Response, field string) string {
+	switch field {
+	case "Status":
+		return r.GetStatusCode()
+	case "Hash":
+		return r.GetHash()
+	case "ETag":
+		return r.GetEtag()
+	case "Last-Modified":
+		return r.GetLastModified()
+	case "Content-Length":
+		return r.GetContentLength()
+	case "Cache-Control":
+		return r.GetCacheControl()
+	}
+	return ""
+}
+
+// Groups the edges by value for each field, the largest group first.
+func groupByField(responses []*Response, field string) FieldComparison {
+	index := map[string]int{}
+	comparison := FieldComparison{Field: field}
+
+	for _, r := range responses {
+		value := fieldValue(r, field)
+		i, ok := index[value]
+		if !ok {
+			i = len(comparison.Groups)
+			index[value] = i
+			comparison.Groups = append(comparison.Groups, EdgeGroup{Value: value})
+		}
+		comparison.Groups[i].Edges = append(comparison.Groups[i].Edges, r.EdgeIP)
+	}
+
+	sort.SliceStable(comparison.Groups, func(i, j int) bool {
+		return len(comparison.Groups[i].Edges) > len(comparison.Groups[j].Edges)
+	})
+
+	return comparison
+}
+
+// Compares the responses of every edge, an edge is stale when its Last-Modified
+// is older than the newest one served, and divergent when a field differs from the majority.
+func CompareResponses(responses []*Response) *Comparison {
+	var succeeded []*Response
+	comparison := &Comparison{}
+
+	for _, r := range responses {
+		if r.Error == nil {
+			succeeded = append(succeeded, r)
+		}
+	}
+
+	for _, field := range comparedFields {
+		comparison.Fields = append(comparison.Fields, groupByField(succeeded, field))
+	}
+
+	var newest time.Time
+	for _, r := range succeeded {
+		if t, err := http.ParseTime(r.GetLastModified()); err == nil && t.After(newest) {
+			newest = t
+		}
+	}
+
+	for _, r := range responses {
+		verdict := EdgeVerdict{EdgeIP: r.EdgeIP, Error: r.Error}
+		if r.Error == nil {
+			for _, field := range comparison.Fields {
+				if fieldValue(r, field.Field) != field.majority() {
+					verdict.Divergent = append(verdict.Divergent, field.Field)
+				}
+			}
+			if t, err := http.ParseTime(r.GetLastModified()); err == nil && t.Before(newest) {
+				verdict.Stale = true
+			}
+		}
+		comparison.Edges = append(comparison.Edges, verdict)
+	}
+
+	return comparison
+}
+
+// Prints every compared field grouped by value and the verdict of each edge.
+func PrintComparison(url string, c *Comparison) {
+	fmt.Printf("\n%s %s %s\n\n", color.HiWhiteString("Comparison of"), color.HiYellowString(url), color.HiWhiteString(fmt.Sprintf("across %d edges", len(c.Edges))))
+
+	for _, field := range c.Fields {
+		if field.Consistent() {
+			PrintFunc(field.Field, color.HiGreenString(emptyValue(field.majority())))
+			continue
+		}
+
+		for i, group := range field.Groups {
+			line := fmt.Sprintf("%s  %s", emptyValue(group.Value), strings.Join(group.Edges, ", "))
+			if i == 0 {
+				PrintFunc(field.Field, color.HiGreenString(line))
+			} else {
+				fmt.Printf("\t\t%s\n", color.HiRedString(line))
+			}
+		}
+	}
+
+	fmt.Printf("\n%s\n", color.HiWhiteString("Edges"))
+	for _, v := range c.Edges {
+		switch {
+		case v.Error != nil:
+			PrintFunc(v.EdgeIP, color.HiRedString("error  %s", v.Error.Error()))
+		case v.Stale:
+			PrintFunc(v.EdgeIP, color.HiRedString("stale  %s", strings.Join(v.Divergent, ", ")))
+		case len(v.Divergent) > 0:
+			PrintFunc(v.EdgeIP, color.HiYellowString("divergent  %s", strings.Join(v.Divergent, ", ")))
+		default:
+			PrintFunc(v.EdgeIP, color.HiGreenString("consistent"))
+		}
+	}
+	fmt.Println()
+}
+
+func emptyValue(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
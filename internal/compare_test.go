@@ -0,0 +1,43 @@
+package internal
+
+import (
+	"fmt"
+	"testing"
+)
+
+// Testing the majority and the stale edge of compared responses.
+func TestCompareResponses(t *testing.T) {
+	fresh := "Tue, 10 Oct 2023 10:00:00 GMT"
+	old := "Mon, 09 Oct 2023 10:00:00 GMT"
+
+	responses := []*Response{
+		{EdgeIP: "1.1.1.1", StatusCode: 200, Etag: `"b"`, LastModified: fresh, Hash: []byte("b")},
+		{EdgeIP: "1.1.1.2", StatusCode: 200, Etag: `"b"`, LastModified: fresh, Hash: []byte("b")},
+		{EdgeIP: "1.1.1.3", StatusCode: 200, Etag: `"a"`, LastModified: old, Hash: []byte("a")},
+		{EdgeIP: "1.1.1.4", Error: fmt.Errorf("connection refused")},
+	}
+
+	c := CompareResponses(responses)
+	if c.Consistent() {
+		t.Fatal("expected an inconsistent comparison")
+	}
+
+	for _, field := range c.Fields {
+		if field.Field == "ETag" && (len(field.Groups) != 2 || field.majority() != `"b"`) {
+			t.Errorf("unexpected ETag groups: %+v", field.Groups)
+		}
+		if field.Field == "Status" && !field.Consistent() {
+			t.Errorf("unexpected Status groups: %+v", field.Groups)
+		}
+	}
+
+	if !c.Edges[0].Consistent() || !c.Edges[1].Consistent() {
+		t.Errorf("expected the majority edges to be consistent: %+v", c.Edges[:2])
+	}
+	if !c.Edges[2].Stale || len(c.Edges[2].Divergent) != 3 {
+		t.Errorf("expected a stale edge diverging on Hash, ETag and Last-Modified: %+v", c.Edges[2])
+	}
+	if c.Edges[3].Error == nil {
+		t.Errorf("expected the failed edge to keep its error")
+	}
+}
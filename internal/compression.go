@@ -0,0 +1,95 @@
+package internal
+
+import (
+	"fmt"
+
+	"github.com/fatih/color"
+)
+
+// Content codings requested explicitly, identity first as the reference size.
+var Encodings = []string{"identity", "gzip", "br", "zstd"}
+
+// Structure with the response of an edge to a single Accept-Encoding.
+type EncodingResult struct {
+	Requested       string
+	ContentEncoding string
+	Size            int64
+	Vary            string
+	Error           error
+}
+
+// Structure with the content negotiation of an edge for every requested coding.
+type CompressionReport struct {
+	EdgeIP  string
+	Results []EncodingResult
+}
+
+// Returns the result of the edge to the coding requested with the response.
+func NewEncodingResult(requested string, r *Response) EncodingResult {
+	result := EncodingResult{Requested: requested, Error: r.Error}
+	if r.Error != nil {
+		return result
+	}
+
+	result.ContentEncoding = r.Header.Get("Content-Encoding")
+	if result.ContentEncoding == "" {
+		result.ContentEncoding = "identity"
+	}
+	result.Size = r.BodySize
+	result.Vary = r.Header.Get("Vary")
+	return result
+}
+
+// Returns the size of the identity response used as the reference of the ratio.
+func (c CompressionReport) identitySize() int64 {
+	for _, r := range c.Results {
+		if r.Error == nil && r.ContentEncoding == "identity" {
+			return r.Size
+		}
+	}
+	return 0
+}
+
+// Reports whether the edge answered with the requested coding.
+func (r EncodingResult) Negotiated() bool {
+	return r.ContentEncoding == r.Requested
+}
+
+// Returns the size of the response as a percentage of the identity size, false without one.
+func (r EncodingResult) ratio(identity int64) (float64, bool) {
+	if identity <= 0 {
+		return 0, false
+	}
+	return float64(r.Size) / float64(identity) * 100, true
+}
+
+// Prints the coding, size, ratio to identity and Vary of each edge per requested coding.
+func PrintCompressionReports(url string, reports []CompressionReport) {
+	fmt.Printf("\n%s %s\n", color.HiWhiteString("Compression of"), color.HiYellowString(url))
+
+	for _, report := range reports {
+		fmt.Printf("\n[%s]\n", color.HiYellowString(report.EdgeIP))
+		identity := report.identitySize()
+
+		for _, r := range report.Results {
+			if r.Error != nil {
+				PrintFunc(r.Requested, color.HiRedString(r.Error.Error()))
+				continue
+			}
+
+			ratio := "-"
+			if percent, ok := r.ratio(identity); ok {
+				ratio = fmt.Sprintf("%.1f%%", percent)
+			}
+
+			line := fmt.Sprintf("%-10s%10d bytes%9s  Vary: %s", r.ContentEncoding, r.Size, ratio, emptyValue(r.Vary))
+			switch {
+			case r.Negotiated():
+				PrintFunc(r.Requested, color.HiGreenString(line))
+			default:
+				PrintFunc(r.Requested, color.HiYellowString(line+"  (not negotiated)"))
+			}
+		}
+	}
+	fmt.Println()
+}
@@ -0,0 +1,59 @@
+package internal
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+)
+
+// Testing the result of each requested coding against the coding the edge negotiated.
+func TestNewEncodingResult(t *testing.T) {
+	tests := []struct {
+		requested  string
+		response   *Response
+		encoding   string
+		negotiated bool
+	}{
+		{"identity", &Response{BodySize: 1000, Header: http.Header{}}, "identity", true},
+		{"gzip", &Response{BodySize: 250, Header: http.Header{"Content-Encoding": {"gzip"}, "Vary": {"Accept-Encoding"}}}, "gzip", true},
+		{"br", &Response{BodySize: 250, Header: http.Header{"Content-Encoding": {"gzip"}}}, "gzip", false},
+		{"zstd", &Response{BodySize: 1000, Header: http.Header{}}, "identity", false},
+		{"gzip", &Response{Error: errors.New("refused")}, "", false},
+	}
+	for _, test := range tests {
+		result := NewEncodingResult(test.requested, test.response)
+		if result.ContentEncoding != test.encoding || result.Negotiated() != test.negotiated || result.Size != test.response.BodySize {
+			t.Errorf("%s: expected %s (negotiated %v), got %+v", test.requested, test.encoding, test.negotiated, result)
+		}
+		if result.Vary != test.response.Header.Get("Vary") {
+			t.Errorf("%s: unexpected Vary %q", test.requested, result.Vary)
+		}
+	}
+}
+
+// Testing the identity size used as the reference of the ratio.
+func TestCompressionRatio(t *testing.T) {
+	tests := []struct {
+		name     string
+		results  []EncodingResult
+		identity int64
+	}{
+		{"identity", []EncodingResult{{Requested: "gzip", ContentEncoding: "gzip", Size: 250}, {Requested: "identity", ContentEncoding: "identity", Size: 1000}}, 1000},
+		{"identity answered to another coding", []EncodingResult{{Requested: "identity", Error: errors.New("refused")}, {Requested: "zstd", ContentEncoding: "identity", Size: 800}}, 800},
+		{"no identity", []EncodingResult{{Requested: "identity", ContentEncoding: "gzip", Size: 250}}, 0},
+	}
+	for _, test := range tests {
+		report := CompressionReport{Results: test.results}
+		if identity := report.identitySize(); identity != test.identity {
+			t.Errorf("%s: expected an identity size of %d, got %d", test.name, test.identity, identity)
+		}
+	}
+
+	gzip := EncodingResult{ContentEncoding: "gzip", Size: 250}
+	if ratio, ok := gzip.ratio(1000); !ok || ratio != 25 {
+		t.Errorf("expected a ratio of 25%%, got %v, %v", ratio, ok)
+	}
+	if _, ok := gzip.ratio(0); ok {
+		t.Error("expected no ratio without an identity size")
+	}
+}
@@ -0,0 +1,245 @@
+package internal
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/fatih/color"
+	bolt "go.etcd.io/bbolt"
+)
+
+// Structure with the result of a probe to an edge kept in the history.
+type HistoryRecord struct {
+	Time       time.Time     `json:"time"`
+	URL        string        `json:"url"`
+	EdgeIP     string        `json:"edge"`
+	Status     int           `json:"status,omitempty"`
+	ErrorKind  string        `json:"error_kind,omitempty"`
+	Hash       string        `json:"hash,omitempty"`
+	Latency    LatencyReport `json:"latency"`
+	Cache      CacheInfo     `json:"cache"`
+	CertSerial string        `json:"cert_serial,omitempty"`
+}
+
+// Returns the record of the response received at the time.
+func NewHistoryRecord(t time.Time, r *Response) HistoryRecord {
+	record := HistoryRecord{Time: t, URL: r.URL, EdgeIP: r.EdgeIP, ErrorKind: r.GetErrorKind()}
+	if r.Error != nil {
+		return record
+	}
+
+	record.Status = r.StatusCode
+	record.Hash = r.GetHash()
+	record.Latency = newLatencyReport(&r.Latency)
+	record.Cache = r.Cache
+	if r.TLS != nil {
+		record.CertSerial = r.TLS.Serial
+	}
+	return record
+}
+
+// Returns the status code, or the category of the error when the probe failed.
+func (r HistoryRecord) GetStatus() string {
+	if r.ErrorKind != "" {
+		return r.ErrorKind
+	}
+	return strconv.Itoa(r.Status)
+}
+
+// History keeps the results of the probes in a bbolt database, with a bucket per url
+// whose keys are the time of the probe followed by the edge so that they sort by time.
+type History struct {
+	db *bolt.DB
+}
+
+// Opens the history at the path, in a gostat.db file of the path when it is a directory.
+// The database is created unless it is opened read only.
+func OpenHistory(path string, readOnly bool) (*History, error) {
+	if info, err := os.Stat(path); err == nil && info.IsDir() {
+		path = filepath.Join(path, "gostat.db")
+	}
+	if readOnly {
+		if _, err := os.Stat(path); err != nil {
+			return nil, err
+		}
+	}
+
+	db, err := bolt.Open(path, 0o644, &bolt.Options{Timeout: time.Second, ReadOnly: readOnly})
+	if err != nil {
+		return nil, fmt.Errorf("open history %s: %w", path, err)
+	}
+	return &History{db: db}, nil
+}
+
+func (h *History) Close() error {
+	return h.db.Close()
+}
+
+func historyKey(t time.Time, edgeIP string) []byte {
+	key := make([]byte, 8, 8+len(edgeIP))
+	binary.BigEndian.PutUint64(key, uint64(t.UnixNano()))
+	return append(key, edgeIP...)
+}
+
+// Appends the records to the history in a single transaction.
+func (h *History) Record(records []HistoryRecord) error {
+	return h.db.Update(func(tx *bolt.Tx) error {
+		for _, record := range records {
+			bucket, err := tx.CreateBucketIfNotExists([]byte(record.URL))
+			if err != nil {
+				return err
+			}
+			value, err := json.Marshal(record)
+			if err != nil {
+				return err
+			}
+			if err := bucket.Put(historyKey(record.Time, record.EdgeIP), value); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Returns the records of the url since the time, in time order.
+func (h *History) Query(url string, since time.Time) ([]HistoryRecord, error) {
+	var records []HistoryRecord
+	err := h.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(url))
+		if bucket == nil {
+			return nil
+		}
+
+		c := bucket.Cursor()
+		for k, v := c.Seek(historyKey(since, "")); k != nil; k, v = c.Next() {
+			var record HistoryRecord
+			if err := json.Unmarshal(v, &record); err != nil {
+				return err
+			}
+			records = append(records, record)
+		}
+		return nil
+	})
+	return records, err
+}
+
+// Structure with a change of the status, hash or certificate of an edge between two probes.
+type HistoryChange struct {
+	Time   time.Time `json:"time"`
+	EdgeIP string    `json:"edge"`
+	Field  string    `json:"field"`
+	From   string    `json:"from"`
+	To     string    `json:"to"`
+}
+
+// Returns the changes of the status, the hash and the certificate serial of each edge,
+// from records in time order. A failed probe changes the status but not the hash or certificate.
+func HistoryChanges(records []HistoryRecord) []HistoryChange {
+	last := map[string]map[string]string{}
+	var changes []HistoryChange
+	for _, r := range records {
+		if last[r.EdgeIP] == nil {
+			last[r.EdgeIP] = map[string]string{}
+		}
+		fields := [][2]string{{"Status", r.GetStatus()}}
+		if r.ErrorKind == "" {
+			fields = append(fields, [2]string{"Hash", r.Hash})
+			if r.CertSerial != "" {
+				fields = append(fields, [2]string{"Certificate", r.CertSerial})
+			}
+		}
+
+		for _, field := range fields {
+			name, value := field[0], field[1]
+			if previous, ok := last[r.EdgeIP][name]; ok && previous != value {
+				changes = append(changes, HistoryChange{Time: r.Time, EdgeIP: r.EdgeIP, Field: name, From: previous, To: value})
+			}
+			last[r.EdgeIP][name] = value
+		}
+	}
+	return changes
+}
+
+// Structure with the average latency of the successful probes of an edge during an interval.
+type LatencyTrend struct {
+	EdgeIP string    `json:"edge"`
+	Start  time.Time `json:"start"`
+	Probes int       `json:"probes"`
+	Mean   float64   `json:"mean_ms"`
+}
+
+// Returns the average total latency of each edge per interval, ordered by edge and then by time.
+func LatencyTrends(records []HistoryRecord, interval time.Duration) []LatencyTrend {
+	type key struct {
+		edge  string
+		start time.Time
+	}
+	sums := map[key]*LatencyTrend{}
+	for _, r := range records {
+		if r.ErrorKind != "" {
+			continue
+		}
+		k := key{r.EdgeIP, r.Time.Truncate(interval)}
+		if sums[k] == nil {
+			sums[k] = &LatencyTrend{EdgeIP: r.EdgeIP, Start: k.start}
+		}
+		sums[k].Probes++
+		sums[k].Mean += r.Latency.Total
+	}
+
+	trends := make([]LatencyTrend, 0, len(sums))
+	for _, trend := range sums {
+		trend.Mean /= float64(trend.Probes)
+		trends = append(trends, *trend)
+	}
+	sort.Slice(trends, func(i, j int) bool {
+		if trends[i].EdgeIP != trends[j].EdgeIP {
+			return compareIPs(trends[i].EdgeIP, trends[j].EdgeIP)
+		}
+		return trends[i].Start.Before(trends[j].Start)
+	})
+	return trends
+}
+
+// Prints the changes of each edge and the trend of its latency.
+func PrintHistory(url string, records []HistoryRecord, changes []HistoryChange, trends []LatencyTrend) {
+	fmt.Printf("\n%s %s\n\n", color.HiWhiteString("History of"), color.HiYellowString(url))
+	if len(records) == 0 {
+		fmt.Println(color.HiBlackString("No probe recorded"))
+		return
+	}
+	PrintFunc("Probes", strconv.Itoa(len(records)))
+	PrintFunc("From", records[0].Time.Local().Format(time.RFC3339))
+	PrintFunc("To", records[len(records)-1].Time.Local().Format(time.RFC3339))
+
+	fmt.Printf("\n%s\n", color.HiWhiteString("Changes"))
+	if len(changes) == 0 {
+		fmt.Println(color.HiGreenString("No change of status, hash or certificate"))
+	}
+	for _, c := range changes {
+		fmt.Printf("%s [%s] %s: %s -> %s\n",
+			color.HiBlackString(c.Time.Local().Format(time.RFC3339)),
+			color.HiYellowString(c.EdgeIP),
+			c.Field,
+			color.HiRedString(c.From),
+			color.HiGreenString(c.To),
+		)
+	}
+
+	fmt.Printf("\n%s\n", color.HiWhiteString("Latency"))
+	edge := ""
+	for _, t := range trends {
+		if t.EdgeIP != edge {
+			edge = t.EdgeIP
+			fmt.Printf("[%s]\n", color.HiYellowString(edge))
+		}
+		PrintFunc(t.Start.Local().Format("2006-01-02 15:04"), fmt.Sprintf("%s over %d probes", color.HiMagentaString("%.1fms", t.Mean), t.Probes))
+	}
+	fmt.Println()
+}
@@ -0,0 +1,71 @@
+package internal
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ghdwlsgur/gostat/pkg/gostat"
+)
+
+// Testing the records of the history with the changes and latency trend of each edge.
+func TestHistory(t *testing.T) {
+	dir := t.TempDir()
+	history, err := OpenHistory(dir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	url := "https://example.com/"
+	start := time.Date(2023, 10, 10, 10, 0, 0, 0, time.UTC)
+	responses := [][]*Response{
+		{{URL: url, EdgeIP: "1.1.1.10", StatusCode: 200, Hash: []byte("a")}, {URL: url, EdgeIP: "1.1.1.2", StatusCode: 200, Hash: []byte("a")}},
+		{{URL: url, EdgeIP: "1.1.1.10", StatusCode: 200, Hash: []byte("b")}, {URL: url, EdgeIP: "1.1.1.2", Error: &gostat.ProbeError{Kind: gostat.ConnectTimeout, Err: errors.New("timeout")}}},
+		{{URL: url, EdgeIP: "1.1.1.10", StatusCode: 200, Hash: []byte("b")}, {URL: url, EdgeIP: "1.1.1.2", StatusCode: 200, Hash: []byte("a")}},
+	}
+	for i, round := range responses {
+		var records []HistoryRecord
+		for _, response := range round {
+			records = append(records, NewHistoryRecord(start.Add(time.Duration(i)*30*time.Minute), response))
+		}
+		if err := history.Record(records); err != nil {
+			t.Fatal(err)
+		}
+	}
+	history.Close()
+
+	history, err = OpenHistory(dir, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer history.Close()
+
+	records, err := history.Query(url, start.Add(time.Minute))
+	if err != nil || len(records) != 4 {
+		t.Fatalf("expected the 4 records after the first round, got %d: %v", len(records), err)
+	}
+	if missing, _ := history.Query("https://example.com/missing", start); missing != nil {
+		t.Errorf("expected no record of another url, got %v", missing)
+	}
+
+	records, _ = history.Query(url, start)
+	changes := HistoryChanges(records)
+	expected := []HistoryChange{
+		{EdgeIP: "1.1.1.10", Field: "Hash"},
+		{EdgeIP: "1.1.1.2", Field: "Status", From: "200", To: "connect_timeout"},
+		{EdgeIP: "1.1.1.2", Field: "Status", From: "connect_timeout", To: "200"},
+	}
+	if len(changes) != len(expected) {
+		t.Fatalf("expected %d changes, got %+v", len(expected), changes)
+	}
+	for i, c := range expected {
+		if changes[i].EdgeIP != c.EdgeIP || changes[i].Field != c.Field || (c.From != "" && (changes[i].From != c.From || changes[i].To != c.To)) {
+			t.Errorf("expected %+v, got %+v", c, changes[i])
+		}
+	}
+
+	trends := LatencyTrends(records, time.Hour)
+	if len(trends) != 4 || trends[0].EdgeIP != "1.1.1.2" || trends[0].Probes != 1 || trends[2].EdgeIP != "1.1.1.10" || trends[2].Probes != 2 {
+		t.Errorf("unexpected trends: %+v", trends)
+	}
+}
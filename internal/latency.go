@@ -1,13 +1,13 @@
 package internal
 
 import (
+	"context"
 	"fmt"
-	"net"
-	"net/http"
 	"strings"
 	"time"
 
 	"github.com/fatih/color"
+	"github.com/ghdwlsgur/gostat/pkg/gostat"
 	ui "github.com/gizak/termui/v3"
 	"github.com/gizak/termui/v3/widgets"
 	"github.com/sirupsen/logrus"
@@ -23,22 +23,17 @@ type Result struct {
 // Terminal ================================================================
 
 func printHttpStatus(url string, result *httpstat.Result, resultC chan<- Result) {
-	var latency time.Duration
-
-	fmt.Println(color.HiWhiteString("Latency Status"))
-	latency += result.DNSLookup
-	printStatusFormat(color.HiWhiteString("DNS Lookup"), color.HiGreenString(result.DNSLookup.String()), color.HiMagentaString(latency.String()))
-	latency += result.TCPConnection
-	printStatusFormat(color.HiWhiteString("TCP Connection"), color.HiGreenString(result.TCPConnection.String()), color.HiMagentaString(latency.String()))
-	latency += result.Connect
-	printStatusFormat(color.HiWhiteString("Connect"), color.HiGreenString(result.Connect.String()), color.HiMagentaString(latency.String()))
-	latency += result.ServerProcessing
-	printStatusFormat(color.HiWhiteString("ServerProcessing"), color.HiGreenString(result.ServerProcessing.String()), color.HiMagentaString(latency.String()))
-
+	latency := printLatencyStatus(result, false)
 	resultC <- Result{url, int(latency / time.Millisecond)}
 }
 
 func printHttpsStatus(url string, result *httpstat.Result, resultC chan<- Result) {
+	latency := printLatencyStatus(result, true)
+	resultC <- Result{url, int(latency / time.Millisecond)}
+}
+
+// Prints each latency step with its cumulative value and returns the sum.
+func printLatencyStatus(result *httpstat.Result, tls bool) time.Duration {
 	var latency time.Duration
 
 	fmt.Println(color.HiWhiteString("Latency Status"))
@@ -46,51 +41,50 @@ func printHttpsStatus(url string, result *httpstat.Result, resultC chan<- Result
 	printStatusFormat(color.HiWhiteString("DNS Lookup"), color.HiGreenString(result.DNSLookup.String()), color.HiMagentaString(latency.String()))
 	latency += result.TCPConnection
 	printStatusFormat(color.HiWhiteString("TCP Connection"), color.HiGreenString(result.TCPConnection.String()), color.HiMagentaString(latency.String()))
-	latency += result.TLSHandshake
-	printStatusFormat(color.HiWhiteString("TLS Handshake"), color.HiGreenString(result.TLSHandshake.String()), color.HiMagentaString(latency.String()))
+	if tls {
+		latency += result.TLSHandshake
+		printStatusFormat(color.HiWhiteString("TLS Handshake"), color.HiGreenString(result.TLSHandshake.String()), color.HiMagentaString(latency.String()))
+	}
 	latency += result.Connect
 	printStatusFormat(color.HiWhiteString("Connect"), color.HiGreenString(result.Connect.String()), color.HiMagentaString(latency.String()))
 	latency += result.ServerProcessing
 	printStatusFormat(color.HiWhiteString("ServerProcessing"), color.HiGreenString(result.ServerProcessing.String()), color.HiMagentaString(latency.String()))
 
-	resultC <- Result{url, int(latency / time.Millisecond)}
+	return latency
 }
 
-func latencyWrapper(url string) {
-	results := make(chan Result)
-	doneC := make(chan struct{})
-
-	go GatherLatencies(url, results, doneC)
-
-	for r := range results {
-		latency := fmt.Sprintf("%dms", r.Latency)
-		fmt.Printf("\t%s\t\t\t\t\t\t%s\n\n", color.HiWhiteString("Total"), color.HiMagentaString(latency))
-	}
+// Prints the latency measured while requesting the edge, followed by the total.
+func printLatency(protocol string, result *httpstat.Result) {
+	latency := printLatencyStatus(result, protocol == "https")
+	total := fmt.Sprintf("%dms", int(latency/time.Millisecond))
+	fmt.Printf("\t%s\t\t\t\t\t\t%s\n\n", color.HiWhiteString("Total"), color.HiMagentaString(total))
 }
 
-// The latency response value is obtained through a channel.
-func GatherLatencies(url string, results chan<- Result, doneC <-chan struct{}) {
-	resultC := make(chan Result)
-	go getLatencies(url, resultC)
+// The latency response value is obtained through a channel, which is closed once the
+// url has been measured, the request failed or the context is done.
+func GatherLatencies(ctx context.Context, url string, results chan<- Result) {
+	defer close(results)
+
+	// Buffered so that the measurement does not block once the context is done.
+	resultC := make(chan Result, 1)
+	go getLatencies(ctx, url, resultC)
 	for {
 		select {
 		case r, ok := <-resultC:
 			if !ok {
-				close(results)
 				return
 			}
 			results <- r
-		case <-doneC:
+		case <-ctx.Done():
 			return
 		}
 	}
 }
 
-func getLatencies(url string, resultC chan<- Result) error {
-	var result *httpstat.Result
-	var err error
+func getLatencies(ctx context.Context, url string, resultC chan<- Result) error {
+	defer close(resultC)
 
-	result, err = getHTTPLatency(url)
+	result, err := getHTTPLatency(ctx, url, &gostat.Prober{})
 	if err != nil {
 		return err
 	}
@@ -103,40 +97,11 @@ func getLatencies(url string, resultC chan<- Result) error {
 		printHttpsStatus(url, result, resultC)
 	}
 
-	close(resultC)
 	return nil
 }
 
-func getHTTPLatency(url string) (*httpstat.Result, error) {
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		logrus.WithFields(logrus.Fields{
-			"error": err,
-			"url":   url,
-		}).Error("Failed to create")
-		return nil, err
-	}
-
-	var result httpstat.Result
-	ctx := httpstat.WithHTTPStat(req.Context(), &result)
-	req = req.WithContext(ctx)
-
-	client := new(http.Client)
-	defer client.CloseIdleConnections()
-
-	client.Transport = &http.Transport{
-		Proxy: http.ProxyFromEnvironment,
-		DialContext: (&net.Dialer{
-			Timeout:   30 * time.Second,
-			KeepAlive: 30 * time.Second,
-		}).DialContext,
-		MaxIdleConns:          100,
-		IdleConnTimeout:       90 * time.Second,
-		TLSHandshakeTimeout:   10 * time.Second,
-		ExpectContinueTimeout: 1 * time.Second,
-	}
-
-	res, err := client.Do(req)
+func getHTTPLatency(ctx context.Context, url string, prober *gostat.Prober) (*httpstat.Result, error) {
+	result, err := prober.Probe(ctx, gostat.ProbeRequest{URL: url})
 	if err != nil {
 		logrus.WithFields(logrus.Fields{
 			"error": err,
@@ -145,42 +110,17 @@ func getHTTPLatency(url string) (*httpstat.Result, error) {
 		return nil, err
 	}
 
-	result.End(time.Now())
-	defer res.Body.Close()
-	return &result, nil
+	return &result.Timings, nil
 }
 
 // DashBoard ================================================================
 
-func latencyTermuiWrapper(url string) {
-	results := make(chan Result)
-	doneC := make(chan struct{})
-
-	go GatherLatenciesOnDashBoard(url, results, doneC)
-}
-
-func GatherLatenciesOnDashBoard(url string, results chan<- Result, doneC <-chan struct{}) {
-	resultC := make(chan Result)
-	go getLatenciesOnDashBoard(url, resultC)
-	for {
-		select {
-		case r, ok := <-resultC:
-			if !ok {
-				close(results)
-				return
-			}
-			results <- r
-		case <-doneC:
-			return
-		}
-	}
+func latencyTermuiWrapper(ctx context.Context, url string, opt *ReqOptions) {
+	go getLatenciesOnDashBoard(ctx, url, opt)
 }
 
-func getLatenciesOnDashBoard(url string, resultC chan<- Result) error {
-	var result *httpstat.Result
-	var err error
-
-	result, err = getHTTPLatency(url)
+func getLatenciesOnDashBoard(ctx context.Context, url string, opt *ReqOptions) error {
+	result, err := getHTTPLatency(ctx, url, opt.prober())
 	if err != nil {
 		return err
 	}
@@ -188,7 +128,6 @@ func getLatenciesOnDashBoard(url string, resultC chan<- Result) error {
 	protocol := strings.Split(url, "://")[0]
 	showLatencyDashBoard(result, protocol)
 
-	close(resultC)
 	return nil
 }
 
@@ -1,15 +1,15 @@
 package internal
 
 import (
+	"context"
 	"testing"
 )
 
 // Testing latency code.
 func TestHttpLatency(t *testing.T) {
 	results := make(chan Result)
-	doneC := make(chan struct{})
 
-	go GatherLatencies("https://www.naver.com", results, doneC)
+	go GatherLatencies(context.Background(), "https://www.naver.com", results)
 
 	for r := range results {
 		t.Log(r.URL, r.Latency)
@@ -0,0 +1,240 @@
+package internal
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Upper bounds in seconds of the buckets of the total latency histogram.
+var LatencyBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+type edgeKey struct {
+	URL    string
+	EdgeIP string
+}
+
+type edgeMetrics struct {
+	success     bool
+	errorKind   string
+	status      int
+	phases      map[string]time.Duration
+	hash        string
+	hashChanges int
+	certExpiry  time.Time
+	cache       CacheInfo
+
+	buckets []uint64
+	count   uint64
+	sum     float64
+}
+
+// Metrics of the last probe of each edge, written in the Prometheus text format.
+type Metrics struct {
+	mu       sync.Mutex
+	resolved map[string]bool
+	edges    map[edgeKey]*edgeMetrics
+}
+
+func NewMetrics() *Metrics {
+	return &Metrics{resolved: map[string]bool{}, edges: map[edgeKey]*edgeMetrics{}}
+}
+
+// Records the responses of a probe of the url, edges that no longer answer for the url are dropped.
+func (m *Metrics) Observe(url string, responses []*Response) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.resolved[url] = true
+	seen := map[edgeKey]bool{}
+	for _, r := range responses {
+		key := edgeKey{URL: url, EdgeIP: r.EdgeIP}
+		seen[key] = true
+
+		e, ok := m.edges[key]
+		if !ok {
+			e = &edgeMetrics{buckets: make([]uint64, len(LatencyBuckets))}
+			m.edges[key] = e
+		}
+		e.observe(r)
+	}
+
+	m.drop(url, seen)
+}
+
+// Records that the target of the url could not be resolved and drops its edges.
+func (m *Metrics) ObserveResolveError(url string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.resolved[url] = false
+	m.drop(url, nil)
+}
+
+func (m *Metrics) drop(url string, keep map[edgeKey]bool) {
+	for key := range m.edges {
+		if key.URL == url && !keep[key] {
+			delete(m.edges, key)
+		}
+	}
+}
+
+func (e *edgeMetrics) observe(r *Response) {
+	e.success = r.Error == nil
+	e.errorKind = r.GetErrorKind()
+	e.phases = map[string]time.Duration{}
+	e.status = 0
+	e.cache = CacheInfo{}
+	e.certExpiry = time.Time{}
+	if !e.success {
+		return
+	}
+
+	e.status = r.StatusCode
+	e.cache = r.Cache
+	if r.TLS != nil {
+		e.certExpiry = r.TLS.NotAfter
+	}
+	for phase, duration := range LatencyPhases {
+		e.phases[phase] = duration(&r.Latency)
+	}
+
+	if hash := r.GetHash(); hash != "" {
+		if e.hash != "" && e.hash != hash {
+			e.hashChanges++
+		}
+		e.hash = hash
+	}
+
+	total := e.phases["total"].Seconds()
+	for i, bound := range LatencyBuckets {
+		if total <= bound {
+			e.buckets[i]++
+		}
+	}
+	e.count++
+	e.sum += total
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// Writes a sample, labels are given as name and value pairs.
+func writeSample(b *strings.Builder, name string, value float64, labels ...string) {
+	b.WriteString(name)
+	if len(labels) > 0 {
+		b.WriteByte('{')
+		for i := 0; i < len(labels); i += 2 {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			fmt.Fprintf(b, `%s="%s"`, labels[i], labelEscaper.Replace(labels[i+1]))
+		}
+		b.WriteByte('}')
+	}
+	b.WriteByte(' ')
+	b.WriteString(strconv.FormatFloat(value, 'g', -1, 64))
+	b.WriteByte('\n')
+}
+
+func boolValue(v bool) float64 {
+	if v {
+		return 1
+	}
+	return 0
+}
+
+// Writes every metric in the Prometheus text exposition format.
+func (m *Metrics) WriteText(w io.Writer) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	keys := make([]edgeKey, 0, len(m.edges))
+	for key := range m.edges {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].URL != keys[j].URL {
+			return keys[i].URL < keys[j].URL
+		}
+		return compareIPs(keys[i].EdgeIP, keys[j].EdgeIP)
+	})
+
+	phases := make([]string, 0, len(LatencyPhases))
+	for phase := range LatencyPhases {
+		phases = append(phases, phase)
+	}
+	sort.Strings(phases)
+
+	urls := make([]string, 0, len(m.resolved))
+	for url := range m.resolved {
+		urls = append(urls, url)
+	}
+	sort.Strings(urls)
+
+	var b strings.Builder
+	b.WriteString("# HELP gostat_resolve_success Whether the A records of the target of the url were resolved.\n# TYPE gostat_resolve_success gauge\n")
+	for _, url := range urls {
+		writeSample(&b, "gostat_resolve_success", boolValue(m.resolved[url]), "url", url)
+	}
+
+	family := func(name, kind, help string, samples func(k edgeKey, e *edgeMetrics)) {
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+		for _, key := range keys {
+			samples(key, m.edges[key])
+		}
+	}
+
+	family("gostat_probe_success", "gauge", "Whether the last request to the edge succeeded.", func(k edgeKey, e *edgeMetrics) {
+		writeSample(&b, "gostat_probe_success", boolValue(e.success), "url", k.URL, "edge", k.EdgeIP)
+	})
+	family("gostat_probe_error", "gauge", "Category of the failure of the last request to the edge.", func(k edgeKey, e *edgeMetrics) {
+		if e.errorKind != "" {
+			writeSample(&b, "gostat_probe_error", 1, "url", k.URL, "edge", k.EdgeIP, "kind", e.errorKind)
+		}
+	})
+	family("gostat_http_status_code", "gauge", "Status code of the last response of the edge.", func(k edgeKey, e *edgeMetrics) {
+		writeSample(&b, "gostat_http_status_code", float64(e.status), "url", k.URL, "edge", k.EdgeIP)
+	})
+	family("gostat_phase_duration_seconds", "gauge", "Latency of each step of the last request to the edge.", func(k edgeKey, e *edgeMetrics) {
+		for _, phase := range phases {
+			if d, ok := e.phases[phase]; ok {
+				writeSample(&b, "gostat_phase_duration_seconds", d.Seconds(), "url", k.URL, "edge", k.EdgeIP, "phase", phase)
+			}
+		}
+	})
+	family("gostat_duration_seconds", "histogram", "Total latency of the requests to the edge.", func(k edgeKey, e *edgeMetrics) {
+		for i, bound := range LatencyBuckets {
+			writeSample(&b, "gostat_duration_seconds_bucket", float64(e.buckets[i]), "url", k.URL, "edge", k.EdgeIP, "le", strconv.FormatFloat(bound, 'g', -1, 64))
+		}
+		writeSample(&b, "gostat_duration_seconds_bucket", float64(e.count), "url", k.URL, "edge", k.EdgeIP, "le", "+Inf")
+		writeSample(&b, "gostat_duration_seconds_sum", e.sum, "url", k.URL, "edge", k.EdgeIP)
+		writeSample(&b, "gostat_duration_seconds_count", float64(e.count), "url", k.URL, "edge", k.EdgeIP)
+	})
+	family("gostat_body_hash_changes_total", "counter", "Number of times the body hash of the edge changed.", func(k edgeKey, e *edgeMetrics) {
+		writeSample(&b, "gostat_body_hash_changes_total", float64(e.hashChanges), "url", k.URL, "edge", k.EdgeIP)
+	})
+	family("gostat_cert_expiry_timestamp_seconds", "gauge", "Expiry of the certificate presented by the edge as a unix timestamp.", func(k edgeKey, e *edgeMetrics) {
+		if !e.certExpiry.IsZero() {
+			writeSample(&b, "gostat_cert_expiry_timestamp_seconds", float64(e.certExpiry.Unix()), "url", k.URL, "edge", k.EdgeIP)
+		}
+	})
+	family("gostat_cache_status", "gauge", "Cache status decoded from the last response of the edge.", func(k edgeKey, e *edgeMetrics) {
+		if e.cache.Status != "" {
+			writeSample(&b, "gostat_cache_status", 1, "url", k.URL, "edge", k.EdgeIP, "vendor", e.cache.Vendor, "status", string(e.cache.Status), "pop", e.cache.PoP)
+		}
+	})
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// Serves the metrics to a Prometheus scrape.
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	m.WriteText(w)
+}
@@ -0,0 +1,65 @@
+package internal
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tcnksm/go-httpstat"
+)
+
+// Testing the Prometheus text output of the probes of each edge.
+func TestMetrics(t *testing.T) {
+	m := NewMetrics()
+	url := "https://example.com/"
+
+	m.Observe(url, []*Response{
+		{EdgeIP: "1.1.1.10", StatusCode: 200, Hash: []byte("a"), Latency: httpstat.Result{ServerProcessing: 30 * time.Millisecond}, Cache: CacheInfo{Vendor: "cloudflare", Status: CacheHit, PoP: "ICN"}},
+		{EdgeIP: "1.1.1.2", Error: fmt.Errorf("connection refused")},
+	})
+	m.Observe(url, []*Response{
+		{EdgeIP: "1.1.1.10", StatusCode: 200, Hash: []byte("b"), Latency: httpstat.Result{ServerProcessing: 2 * time.Second}, TLS: &TLSInfo{NotAfter: time.Unix(1700000000, 0)}},
+		{EdgeIP: "1.1.1.2", Error: fmt.Errorf("connection refused")},
+	})
+
+	var buf bytes.Buffer
+	if err := m.WriteText(&buf); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+
+	for _, expected := range []string{
+		"# TYPE gostat_probe_success gauge\ngostat_probe_success{url=\"https://example.com/\",edge=\"1.1.1.2\"} 0\ngostat_probe_success{url=\"https://example.com/\",edge=\"1.1.1.10\"} 1\n",
+		`gostat_http_status_code{url="https://example.com/",edge="1.1.1.10"} 200`,
+		`gostat_probe_error{url="https://example.com/",edge="1.1.1.2",kind="error"} 1`,
+		`gostat_phase_duration_seconds{url="https://example.com/",edge="1.1.1.10",phase="server_processing"} 2`,
+		`gostat_duration_seconds_bucket{url="https://example.com/",edge="1.1.1.10",le="0.05"} 1`,
+		`gostat_duration_seconds_bucket{url="https://example.com/",edge="1.1.1.10",le="+Inf"} 2`,
+		`gostat_duration_seconds_count{url="https://example.com/",edge="1.1.1.10"} 2`,
+		`gostat_body_hash_changes_total{url="https://example.com/",edge="1.1.1.10"} 1`,
+		`gostat_cert_expiry_timestamp_seconds{url="https://example.com/",edge="1.1.1.10"} 1.7e+09`,
+	} {
+		if !strings.Contains(out, expected) {
+			t.Errorf("expected %s in:\n%s", expected, out)
+		}
+	}
+	if strings.Contains(out, `gostat_cache_status{`) {
+		t.Errorf("expected the cache status of the earlier probe to be cleared:\n%s", out)
+	}
+
+	m.Observe(url, []*Response{{EdgeIP: "1.1.1.10", StatusCode: 200}})
+	buf.Reset()
+	m.WriteText(&buf)
+	if strings.Contains(buf.String(), `edge="1.1.1.2"`) {
+		t.Errorf("expected the edge that is no longer resolved to be dropped:\n%s", buf.String())
+	}
+
+	m.ObserveResolveError(url)
+	buf.Reset()
+	m.WriteText(&buf)
+	if !strings.Contains(buf.String(), `gostat_resolve_success{url="https://example.com/"} 0`) || strings.Contains(buf.String(), `edge=`) {
+		t.Errorf("expected the failed resolution to drop every edge:\n%s", buf.String())
+	}
+}
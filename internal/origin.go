@@ -0,0 +1,129 @@
+package internal
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+// Headers that change on every response or are added per hop, so they are not diffed against origin.
+var volatileHeaders = map[string]bool{
+	"Age":               true,
+	"Connection":        true,
+	"Date":              true,
+	"Keep-Alive":        true,
+	"Server-Timing":     true,
+	"Transfer-Encoding": true,
+	"Via":               true,
+	"X-Cache":           true,
+	"X-Cache-Hits":      true,
+	"X-Served-By":       true,
+	"X-Timer":           true,
+	"Cf-Ray":            true,
+	"Cf-Cache-Status":   true,
+	"X-Amz-Cf-Id":       true,
+	"X-Amz-Cf-Pop":      true,
+}
+
+// Difference of a field between the origin and an edge, an empty value means the field is missing.
+type FieldDiff struct {
+	Field  string
+	Origin string
+	Edge   string
+}
+
+// Structure with every difference between the origin and an edge.
+type OriginDiff struct {
+	EdgeIP string
+	Diffs  []FieldDiff
+	Error  error
+}
+
+func (d OriginDiff) Identical() bool {
+	return d.Error == nil && len(d.Diffs) == 0
+}
+
+// Diffs status, body hash, body size and the non-volatile headers of every edge against the origin.
+func DiffAgainstOrigin(origin *Response, edges []*Response) []OriginDiff {
+	var diffs []OriginDiff
+
+	for _, edge := range edges {
+		diff := OriginDiff{EdgeIP: edge.EdgeIP, Error: edge.Error}
+		if edge.Error == nil {
+			diff.Diffs = diffResponse(origin, edge)
+		}
+		diffs = append(diffs, diff)
+	}
+
+	return diffs
+}
+
+func diffResponse(origin, edge *Response) []FieldDiff {
+	var diffs []FieldDiff
+
+	add := func(field, a, b string) {
+		if a != b {
+			diffs = append(diffs, FieldDiff{Field: field, Origin: a, Edge: b})
+		}
+	}
+	add("Status", origin.GetStatusCode(), edge.GetStatusCode())
+	add("Hash", origin.GetHash(), edge.GetHash())
+	add("Size", origin.GetBodySize(), edge.GetBodySize())
+
+	for _, name := range headerNames(origin.Header, edge.Header) {
+		add(name, strings.Join(origin.Header.Values(name), ", "), strings.Join(edge.Header.Values(name), ", "))
+	}
+
+	return diffs
+}
+
+// Returns the sorted union of the header names of both responses without the volatile ones.
+func headerNames(a, b http.Header) []string {
+	seen := map[string]bool{}
+	for _, header := range []http.Header{a, b} {
+		for name := range header {
+			if !volatileHeaders[http.CanonicalHeaderKey(name)] {
+				seen[http.CanonicalHeaderKey(name)] = true
+			}
+		}
+	}
+
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// Prints whether each edge serves what the origin serves, with the differing fields.
+func PrintOriginDiff(url string, origin *Response, diffs []OriginDiff) {
+	fmt.Printf("\n%s %s %s\n\n", color.HiWhiteString("Comparison of"), color.HiYellowString(url), color.HiWhiteString("against origin"))
+	PrintFunc("Origin", color.HiYellowString(origin.EdgeIP))
+	PrintFunc("Status", origin.GetStatusCode())
+	PrintFunc("Hash", origin.GetHash())
+	PrintFunc("Size", origin.GetBodySize())
+
+	for _, d := range diffs {
+		fmt.Printf("\n[%s]\n", color.HiYellowString(d.EdgeIP))
+		switch {
+		case d.Error != nil:
+			PrintFunc("Error", color.HiRedString(d.Error.Error()))
+		case d.Identical():
+			PrintFunc("Result", color.HiGreenString("identical to origin"))
+		default:
+			for _, f := range d.Diffs {
+				field := f.Field
+				if len(field) > 14 {
+					field = stringFormat(field)
+				}
+				PrintFunc(field, fmt.Sprintf("%s -> %s", color.HiGreenString(emptyValue(f.Origin)), color.HiRedString(emptyValue(f.Edge))))
+			}
+		}
+	}
+	fmt.Println()
+}
@@ -0,0 +1,57 @@
+package internal
+
+import (
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+// Testing that volatile headers are ignored and that status, hash, size and header differences are reported.
+func TestDiffAgainstOrigin(t *testing.T) {
+	origin := &Response{
+		StatusCode: 200,
+		Hash:       []byte("origin"),
+		BodySize:   10,
+		Header:     http.Header{"Date": {"Tue, 10 Oct 2023 10:00:00 GMT"}, "Content-Type": {"text/html"}, "Cache-Control": {"max-age=60"}},
+	}
+	same := &Response{
+		EdgeIP:     "1.1.1.1",
+		StatusCode: 200,
+		Hash:       []byte("origin"),
+		BodySize:   10,
+		Header:     http.Header{"Date": {"Tue, 10 Oct 2023 11:00:00 GMT"}, "Age": {"120"}, "X-Cache": {"HIT"}, "Content-Type": {"text/html"}, "Cache-Control": {"max-age=60"}},
+	}
+	different := &Response{
+		EdgeIP:     "2.2.2.2",
+		StatusCode: 404,
+		Hash:       []byte("edge"),
+		BodySize:   10,
+		Header:     http.Header{"Content-Type": {"text/plain"}, "X-Extra": {"1"}},
+	}
+	failed := &Response{EdgeIP: "3.3.3.3", Error: errors.New("refused")}
+
+	diffs := DiffAgainstOrigin(origin, []*Response{same, different, failed})
+	if len(diffs) != 3 {
+		t.Fatalf("expected a diff per edge, got %d", len(diffs))
+	}
+
+	if !diffs[0].Identical() {
+		t.Errorf("expected volatile headers to be ignored, got %+v", diffs[0].Diffs)
+	}
+
+	want := []FieldDiff{
+		{Field: "Status", Origin: "200", Edge: "404"},
+		{Field: "Hash", Origin: origin.GetHash(), Edge: different.GetHash()},
+		{Field: "Cache-Control", Origin: "max-age=60", Edge: ""},
+		{Field: "Content-Type", Origin: "text/html", Edge: "text/plain"},
+		{Field: "X-Extra", Origin: "", Edge: "1"},
+	}
+	if !reflect.DeepEqual(diffs[1].Diffs, want) {
+		t.Errorf("unexpected diffs:\n got %+v\nwant %+v", diffs[1].Diffs, want)
+	}
+
+	if diffs[2].Identical() || diffs[2].Error == nil || diffs[2].Diffs != nil {
+		t.Errorf("expected the error of the edge without diffs, got %+v", diffs[2])
+	}
+}
@@ -0,0 +1,141 @@
+package internal
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+// The version of the contents before the purge, given as a time, an ETag or a body hash.
+type PurgeCondition struct {
+	Since   time.Time
+	OldEtag string
+	OldHash string
+}
+
+// Parses the purge reference, a time in RFC 3339 or HTTP date format, a body hash printed by gostat or an ETag.
+func ParsePurgeSince(since string) (PurgeCondition, error) {
+	if since == "" {
+		return PurgeCondition{}, fmt.Errorf("the purge reference is required")
+	}
+	if t, err := time.Parse(time.RFC3339, since); err == nil {
+		return PurgeCondition{Since: t}, nil
+	}
+	if t, err := http.ParseTime(since); err == nil {
+		return PurgeCondition{Since: t}, nil
+	}
+	if sum, err := base64.StdEncoding.DecodeString(since); err == nil && len(sum) == 32 {
+		return PurgeCondition{OldHash: since}, nil
+	}
+	return PurgeCondition{OldEtag: since}, nil
+}
+
+// Returns the time the response was stored in the cache, from its Date minus its Age.
+// It is unknown without an Age, since the Date of an edge that does not send one is
+// the time of the response rather than the time it was cached.
+func cachedAt(r *Response) (time.Time, bool) {
+	date, err := http.ParseTime(r.GetDate())
+	if err != nil {
+		return time.Time{}, false
+	}
+	age, err := strconv.Atoi(r.GetAge())
+	if err != nil {
+		return time.Time{}, false
+	}
+	return date.Add(-time.Duration(age) * time.Second), true
+}
+
+// Returns the ETag without its weak prefix, which edges add when they compress the object.
+func strongEtag(etag string) string {
+	return strings.TrimPrefix(etag, "W/")
+}
+
+// Reports whether the response reflects the version published after the purge.
+func (c PurgeCondition) Converged(r *Response) bool {
+	if r.Error != nil {
+		return false
+	}
+
+	switch {
+	case c.OldHash != "":
+		return r.GetHash() != c.OldHash
+	case c.OldEtag != "":
+		return r.GetEtag() != "" && strongEtag(r.GetEtag()) != strongEtag(c.OldEtag)
+	}
+
+	if t, err := http.ParseTime(r.GetLastModified()); err == nil && !t.Before(c.Since) {
+		return true
+	}
+	if t, ok := cachedAt(r); ok && !t.Before(c.Since) {
+		return true
+	}
+	return false
+}
+
+// Structure with the convergence of an edge after a purge.
+type PurgeResult struct {
+	EdgeIP    string
+	Converged bool
+	Attempts  int
+	Elapsed   time.Duration
+	Last      *Response
+}
+
+// Polls the edge every interval until the condition converges, the deadline passes or the context is done.
+func PollPurge(ctx context.Context, edgeIP string, fetch func() *Response, condition PurgeCondition, interval time.Duration, deadline time.Time) PurgeResult {
+	result := PurgeResult{EdgeIP: edgeIP}
+	start := time.Now()
+
+	for {
+		result.Last = fetch()
+		result.Attempts++
+		if condition.Converged(result.Last) {
+			result.Converged = true
+			break
+		}
+		if time.Now().Add(interval).After(deadline) || !sleep(ctx, interval) {
+			break
+		}
+	}
+	result.Elapsed = time.Since(start)
+
+	return result
+}
+
+// Prints the convergence time of each edge and the edges still stale after the deadline.
+func PrintPurgeResults(url string, results []PurgeResult) {
+	fmt.Printf("\n%s %s\n", color.HiWhiteString("Purge of"), color.HiYellowString(url))
+
+	var stale []string
+	for _, r := range results {
+		fmt.Printf("\n[%s]\n", color.HiYellowString(r.EdgeIP))
+		if r.Converged {
+			PrintFunc("Result", color.HiGreenString("converged after %s (%d requests)", r.Elapsed.Round(time.Millisecond), r.Attempts))
+		} else {
+			stale = append(stale, r.EdgeIP)
+			PrintFunc("Result", color.HiRedString("still stale after %s (%d requests)", r.Elapsed.Round(time.Millisecond), r.Attempts))
+		}
+
+		if r.Last.Error != nil {
+			PrintFunc("Error", color.HiRedString(r.Last.Error.Error()))
+			continue
+		}
+		PrintFunc("ETag", emptyValue(r.Last.GetEtag()))
+		PrintFunc("Last-Modified", emptyValue(r.Last.GetLastModified()))
+		PrintFunc("Age", emptyValue(r.Last.GetAge()))
+		PrintFunc("Hash", r.Last.GetHash())
+	}
+
+	fmt.Println()
+	if len(stale) == 0 {
+		fmt.Println(color.HiGreenString("All %d edges converged", len(results)))
+	} else {
+		fmt.Println(color.HiRedString("%d of %d edges are still stale", len(stale), len(results)))
+	}
+}
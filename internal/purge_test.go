@@ -0,0 +1,39 @@
+package internal
+
+import (
+	"testing"
+)
+
+// Testing the convergence of responses against each kind of purge reference.
+func TestPurgeCondition(t *testing.T) {
+	byTime, err := ParsePurgeSince("2023-10-10T10:00:00Z")
+	if err != nil || byTime.Since.IsZero() {
+		t.Fatalf("expected a time reference: %+v, %v", byTime, err)
+	}
+
+	modified := &Response{LastModified: "Tue, 10 Oct 2023 10:30:00 GMT"}
+	stale := &Response{LastModified: "Mon, 09 Oct 2023 10:00:00 GMT", Date: "Tue, 10 Oct 2023 11:00:00 GMT", Age: "7200"}
+	refetched := &Response{LastModified: "Mon, 09 Oct 2023 10:00:00 GMT", Date: "Tue, 10 Oct 2023 11:00:00 GMT", Age: "60"}
+	withoutAge := &Response{LastModified: "Mon, 09 Oct 2023 10:00:00 GMT", Date: "Tue, 10 Oct 2023 11:00:00 GMT"}
+	if !byTime.Converged(modified) || byTime.Converged(stale) || !byTime.Converged(refetched) || byTime.Converged(withoutAge) {
+		t.Error("unexpected convergence by time")
+	}
+
+	byEtag, _ := ParsePurgeSince(`"abc"`)
+	if byEtag.OldEtag != `"abc"` || byEtag.Converged(&Response{Etag: `"abc"`}) || !byEtag.Converged(&Response{Etag: `"def"`}) {
+		t.Errorf("unexpected convergence by ETag: %+v", byEtag)
+	}
+	if byEtag.Converged(&Response{Etag: `W/"abc"`}) || !byEtag.Converged(&Response{Etag: `W/"def"`}) {
+		t.Error("expected a weak ETag to match the strong ETag of the same version")
+	}
+	byWeakEtag, _ := ParsePurgeSince(`W/"abc"`)
+	if byWeakEtag.Converged(&Response{Etag: `"abc"`}) || !byWeakEtag.Converged(&Response{Etag: `"def"`}) {
+		t.Errorf("unexpected convergence by weak ETag: %+v", byWeakEtag)
+	}
+
+	old := &Response{Hash: make([]byte, 32)}
+	byHash, _ := ParsePurgeSince(old.GetHash())
+	if byHash.OldHash == "" || byHash.Converged(old) || !byHash.Converged(&Response{Hash: []byte("new")}) {
+		t.Errorf("unexpected convergence by hash: %+v", byHash)
+	}
+}
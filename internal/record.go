@@ -1,14 +1,39 @@
 package internal
 
 import (
+	"bytes"
+	"context"
 	"net"
+	"sort"
+	"time"
 )
 
 // Get only ipv4 values, not ipv6
-func GetRecordIPv4(domainName string) ([]string, error) {
-	// use system DNS resolver
-	net.DefaultResolver.PreferGo = false
-	ips, err := net.LookupIP(domainName)
+func GetRecordIPv4(ctx context.Context, domainName string) ([]string, error) {
+	return GetRecordIPv4On(ctx, "", domainName)
+}
+
+// Get only ipv4 values using the DNS server at resolver (host:port), or the system DNS resolver when it is empty.
+func GetRecordIPv4On(ctx context.Context, resolver, domainName string) ([]string, error) {
+	var ips []net.IP
+	var err error
+
+	if resolver == "" {
+		// use system DNS resolver
+		net.DefaultResolver.PreferGo = false
+		ips, err = net.DefaultResolver.LookupIP(ctx, "ip", domainName)
+	} else {
+		if _, _, splitErr := net.SplitHostPort(resolver); splitErr != nil {
+			resolver = net.JoinHostPort(resolver, "53")
+		}
+		r := &net.Resolver{
+			PreferGo: true,
+			Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+				return (&net.Dialer{Timeout: 5 * time.Second}).DialContext(ctx, network, resolver)
+			},
+		}
+		ips, err = r.LookupIP(ctx, "ip4", domainName)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -22,3 +47,24 @@ func GetRecordIPv4(domainName string) ([]string, error) {
 
 	return ipList, nil
 }
+
+// Returns a copy of the ip list sorted in ascending numeric order.
+func SortIPs(ips []string) []string {
+	sorted := make([]string, len(ips))
+	copy(sorted, ips)
+
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return compareIPs(sorted[i], sorted[j])
+	})
+
+	return sorted
+}
+
+// Reports whether the address x sorts before y, numerically when both are IP addresses.
+func compareIPs(x, y string) bool {
+	a, b := net.ParseIP(x), net.ParseIP(y)
+	if a == nil || b == nil {
+		return x < y
+	}
+	return bytes.Compare(a.To16(), b.To16()) < 0
+}
@@ -0,0 +1,88 @@
+package internal
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/tcnksm/go-httpstat"
+)
+
+// Structure with the latency of each step in milliseconds, written in json output.
+type LatencyReport struct {
+	DNSLookup        float64 `json:"dns_lookup_ms"`
+	TCPConnection    float64 `json:"tcp_connection_ms"`
+	TLSHandshake     float64 `json:"tls_handshake_ms"`
+	ServerProcessing float64 `json:"server_processing_ms"`
+	Total            float64 `json:"total_ms"`
+}
+
+// Structure with the fields of a response written in json output.
+type ResponseReport struct {
+	URL      string        `json:"url"`
+	EdgeIP   string        `json:"edge"`
+	Protocol string        `json:"protocol"`
+	Status   int           `json:"status"`
+	Header   http.Header   `json:"header,omitempty"`
+	Hash     string        `json:"hash,omitempty"`
+	Size     int64         `json:"size"`
+	Cache    CacheInfo     `json:"cache"`
+	Latency  LatencyReport `json:"latency"`
+	TLS      *TLSInfo      `json:"tls,omitempty"`
+	Error    string        `json:"error,omitempty"`
+	// Category of the error, such as dns_failure, connect_timeout or cert_invalid.
+	ErrorKind string `json:"error_kind,omitempty"`
+	// Every request to the edge when retries are enabled, the fields above are of the last one.
+	Attempts []AttemptReport `json:"attempts,omitempty"`
+}
+
+// Structure with the outcome of a single request to an edge written in json output.
+type AttemptReport struct {
+	Status    int     `json:"status,omitempty"`
+	ErrorKind string  `json:"error_kind,omitempty"`
+	Latency   float64 `json:"latency_ms"`
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+func newLatencyReport(result *httpstat.Result) LatencyReport {
+	total := result.DNSLookup + result.TCPConnection + result.TLSHandshake + result.ServerProcessing
+	return LatencyReport{
+		DNSLookup:        milliseconds(result.DNSLookup),
+		TCPConnection:    milliseconds(result.TCPConnection),
+		TLSHandshake:     milliseconds(result.TLSHandshake),
+		ServerProcessing: milliseconds(result.ServerProcessing),
+		Total:            milliseconds(total),
+	}
+}
+
+// Returns the response as a structure that can be written in json output.
+func (r *Response) Report() ResponseReport {
+	report := ResponseReport{
+		URL:      r.URL,
+		EdgeIP:   r.EdgeIP,
+		Protocol: r.Protocol,
+	}
+	for _, attempt := range r.Attempts {
+		report.Attempts = append(report.Attempts, AttemptReport{
+			Status:    attempt.StatusCode,
+			ErrorKind: attempt.ErrorKind,
+			Latency:   milliseconds(attempt.Latency),
+		})
+	}
+	if r.Error != nil {
+		report.Error = r.Error.Error()
+		report.ErrorKind = r.GetErrorKind()
+		return report
+	}
+
+	report.Status = r.StatusCode
+	report.Header = r.Header
+	report.Hash = r.GetHash()
+	report.Size = r.BodySize
+	report.Cache = r.Cache
+	report.Latency = newLatencyReport(&r.Latency)
+	report.TLS = r.TLS
+	return report
+}
@@ -2,32 +2,36 @@ package internal
 
 import (
 	"context"
-	"crypto/sha256"
-	"crypto/tls"
 	"encoding/base64"
 	"fmt"
-	"io"
-	"net"
 	"net/http"
-	"net/url"
+	"sort"
 	"strconv"
 	"time"
 
 	"github.com/fatih/color"
+	"github.com/ghdwlsgur/gostat/pkg/gostat"
 	"github.com/miekg/dns"
 	"github.com/tcnksm/go-httpstat"
 )
 
-// A structure with fields required for request options, range is fixed as byte=0-1 by default.
+// A structure with fields required for request options, range is fixed as byte=0-1 by default unless the full body is requested.
 type ReqOptions struct {
-	Host          string `json:"domain-host"`
-	Authorization string `json:"authorization"`
-	Referer       string `json:"referer"`
-	ByteRange     string `json:"range"`
-	Port          int    `json:"port"`
-	Transport     http.Transport
-	AttackMode    bool `json:"attack-mode"`
-	RequestCount  int
+	Host          string      `json:"domain-host"`
+	Authorization string      `json:"authorization"`
+	Referer       string      `json:"referer"`
+	ByteRange     string      `json:"range"`
+	Port          int         `json:"port"`
+	AttackMode    bool        `json:"attack-mode"`
+	FullBody      bool        `json:"full-body"`
+	BodyLimit     int64       `json:"body-limit"`
+	Header        http.Header `json:"header"`
+	RawEncoding   bool        `json:"raw-encoding"`
+	// Timeouts of establishing the connection and of the whole request to an edge.
+	ConnectTimeout time.Duration `json:"connect-timeout"`
+	Timeout        time.Duration `json:"timeout"`
+	Retry          RetryPolicy   `json:"retry"`
+	RequestCount   int
 }
 
 type Response struct {
@@ -44,11 +48,27 @@ type Response struct {
 	ACAOrigin     string `json:"Access-Control-Allow-Origin"`
 	Via           string `json:"Via"`
 	EdgeIP        string
+	URL           string
 	Hash          []byte
+	BodySize      int64
+	Body          []byte
+	Status        string
+	Protocol      string
+	Header        http.Header
+	RequestHeader http.Header
+	Latency       httpstat.Result
+	Cache         CacheInfo
+	TLS           *TLSInfo
 	Error         error
+	// Every request to the edge in order, set when retries are enabled.
+	Attempts []Attempt
 }
 
+// Returns the status code, or the category of the error when the request failed.
 func (r Response) GetStatusCode() string {
+	if r.Error != nil {
+		return r.GetErrorKind()
+	}
 	return strconv.Itoa(r.StatusCode)
 }
 
@@ -115,10 +135,23 @@ func (r Response) GetVia() string {
 	return r.Via
 }
 
+func (r Response) GetCacheStatus() string {
+	return r.Cache.String()
+}
+
+func (r Response) GetBodySize() string {
+	return strconv.FormatInt(r.BodySize, 10)
+}
+
 func (r Response) GetHash() string {
 	return base64.StdEncoding.EncodeToString(r.Hash)
 }
 
+// Returns the category of the error of the response, empty when the request succeeded.
+func (r Response) GetErrorKind() string {
+	return string(gostat.KindOf(r.Error))
+}
+
 // Structure with fields for address information.
 type Address struct {
 	IP         string `json:"ip"`
@@ -160,12 +193,49 @@ func (ro *ReqOptions) getAttackMode() bool {
 	return ro.AttackMode
 }
 
-func (ro *ReqOptions) getTransport() http.Transport {
-	return *ro.Transport.Clone()
+func (ro *ReqOptions) getFullBody() bool {
+	return ro.FullBody
+}
+
+func (ro *ReqOptions) getBodyLimit() int64 {
+	return ro.BodyLimit
+}
+
+func (ro *ReqOptions) getRawEncoding() bool {
+	return ro.RawEncoding
+}
+
+func (ro *ReqOptions) getHeader() http.Header {
+	return ro.Header
 }
 
-func (ro *ReqOptions) getRequestCount() int {
-	return ro.RequestCount
+// Returns a copy of the options, to vary the request per edge or per attempt.
+func (ro *ReqOptions) Clone() *ReqOptions {
+	return &ReqOptions{
+		Host:           ro.Host,
+		Authorization:  ro.Authorization,
+		Referer:        ro.Referer,
+		ByteRange:      ro.ByteRange,
+		Port:           ro.Port,
+		AttackMode:     ro.AttackMode,
+		FullBody:       ro.FullBody,
+		BodyLimit:      ro.BodyLimit,
+		Header:         ro.Header.Clone(),
+		RawEncoding:    ro.RawEncoding,
+		ConnectTimeout: ro.ConnectTimeout,
+		Timeout:        ro.Timeout,
+		Retry:          ro.Retry,
+		RequestCount:   ro.RequestCount,
+	}
+}
+
+// Returns the library prober with the timeouts of the options.
+func (ro *ReqOptions) prober() *gostat.Prober {
+	return &gostat.Prober{
+		DialTimeout:         ro.ConnectTimeout,
+		TLSHandshakeTimeout: ro.ConnectTimeout,
+		Timeout:             ro.Timeout,
+	}
 }
 
 func (ro *ReqOptions) GetRequestCount() string {
@@ -189,225 +259,134 @@ func (addr Address) getTarget() string {
 }
 
 // Applied when using HTTP protocol.
-func ResolveHTTP(addr *Address, opt *ReqOptions) error {
-
-	netURL := url.URL{}
-	ref := fmt.Sprintf("http://%s:%v@%s:%v", addr.getDomainName(), opt.getPort(), addr.getIP(), opt.getPort())
-	urlProxy, err := netURL.Parse(ref)
-	if err != nil {
-		return err
-	}
-
-	client := &http.Client{
-		Transport: &http.Transport{
-			Dial: (&net.Dialer{
-				Timeout: 5 * time.Second,
-			}).Dial,
-			TLSHandshakeTimeout: 5 * time.Second,
-			Proxy:               http.ProxyURL(urlProxy),
-		},
-	}
-
-	urlDomain := fmt.Sprintf("http://%s", addr.Url)
-	req, err := http.NewRequest("GET", urlDomain, nil)
-	if err != nil {
-		panic(err)
-	}
-
-	var result httpstat.Result
-	ctx := httpstat.WithHTTPStat(req.Context(), &result)
-	req = req.WithContext(ctx)
-
-	addRequestHeader(req, opt.getHost(), opt.getReferer(), opt.getAuthorization(), opt.getAttackMode())
-
-	resp, err := client.Do(req)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-
-	if !opt.getAttackMode() {
-		if addr.getTarget() != addr.getIP() {
-			fmt.Printf("\n%s - [%s]\n\n", color.HiYellowString(addr.getTarget()), color.HiYellowString(addr.getIP()))
-		} else {
-			fmt.Printf("\n[%s]\n\n", color.HiYellowString(addr.getTarget()))
-		}
-
-		// ips, _ := GetRecordIPv4(urlDomain)
-		latencyWrapper(urlDomain)
-
-		fmt.Printf("%s\n", color.HiWhiteString("Request Headers"))
-		setRequestHeader(resp)
-
-		res := &ResolveResponse{
-			respStatus: resp.Status,
-		}
-
-		fmt.Printf("%s\n", color.HiWhiteString("Response Headers"))
-		printStatusToColor(res.getRespStatus())
-
-		printResponse(resp)
-	} else {
-		fmt.Printf("\r%s: %v, %s: %d",
-			color.HiBlackString("Status Code"),
-			resp.StatusCode,
-			color.HiBlackString("Reqeust Count"),
-			opt.getRequestCount())
+func ResolveHTTP(ctx context.Context, addr *Address, opt *ReqOptions) error {
+	response := FetchHTTP(ctx, addr, opt)
+	if response.Error != nil {
+		return response.Error
 	}
 
+	PrintResponse(addr, opt, response)
 	return nil
 }
 
 // Applied when using HTTPS protocol.
-func ResolveHTTPS(addr *Address, opt *ReqOptions) error {
-
-	transport := SetTransport(addr.getUrl(), addr.getIP())
-	conn, err := tls.Dial("tcp", fmt.Sprintf("%s:443", addr.getDomainName()), transport.TLSClientConfig)
-
-	if err != nil {
-		return err
+func ResolveHTTPS(ctx context.Context, addr *Address, opt *ReqOptions) error {
+	response := FetchHTTPS(ctx, addr, opt)
+	if response.Error != nil {
+		return response.Error
 	}
-	defer conn.Close()
 
-	client := &http.Client{Transport: &transport}
+	PrintResponse(addr, opt, response)
+	return nil
+}
 
-	url := fmt.Sprintf("https://%s", addr.getUrl())
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		panic(err)
+// Print the response of an edge.
+func PrintResponse(addr *Address, opt *ReqOptions, response *Response) {
+	if addr.getTarget() != response.EdgeIP {
+		fmt.Printf("\n%s - [%s]\n\n", color.HiYellowString(addr.getTarget()), color.HiYellowString(response.EdgeIP))
+	} else {
+		fmt.Printf("\n[%s]\n\n", color.HiYellowString(addr.getTarget()))
 	}
+	printLatency(response.Protocol, &response.Latency)
 
-	// request
-	var result httpstat.Result
-	ctx := httpstat.WithHTTPStat(req.Context(), &result)
-	req = req.WithContext(ctx)
-
-	addRequestHeader(req, opt.getHost(), opt.getReferer(), opt.getAuthorization(), opt.getAttackMode())
+	fmt.Printf("%s\n", color.HiWhiteString("Request Headers"))
+	setRequestHeader(response.RequestHeader)
 
-	// response
-	resp, err := client.Do(req)
-	if err != nil {
-		return err
+	res := &ResolveResponse{
+		respStatus: response.Status,
 	}
-	defer resp.Body.Close()
-
-	if !opt.getAttackMode() {
-		if addr.getTarget() != addr.getIP() {
-			fmt.Printf("\n%s - [%s]\n\n", color.HiYellowString(addr.getTarget()), color.HiYellowString(addr.getIP()))
-		} else {
-			fmt.Printf("\n[%s]\n\n", color.HiYellowString(addr.getTarget()))
-		}
-		latencyWrapper(url)
 
-		fmt.Printf("%s\n", color.HiWhiteString("Request Headers"))
-		setRequestHeader(resp)
+	fmt.Printf("%s\n", color.HiWhiteString("Response Headers"))
+	printStatusToColor(res.getRespStatus())
+	printResponse(response.Header)
+	printAttempts(response)
 
-		res := &ResolveResponse{
-			respStatus: resp.Status,
+	if response.Cache.Status != CacheUnknown {
+		fmt.Printf("%s\n", color.HiWhiteString("Cache Status"))
+		PrintFunc("Cache", response.GetCacheStatus())
+		if response.Cache.Vendor != "" {
+			PrintFunc("Vendor", response.Cache.Vendor)
 		}
-
-		fmt.Printf("%s\n", color.HiWhiteString("Response Headers"))
-		printStatusToColor(res.getRespStatus())
-		printResponse(resp)
-	} else {
-		fmt.Printf("\r%s: %v, %s: %d",
-			color.HiBlackString("Status Code"),
-			resp.StatusCode,
-			color.HiBlackString("Reqeust Count"),
-			opt.getRequestCount(),
-		)
+		fmt.Println()
 	}
-
-	return nil
 }
 
-func SetTransport(domainName, ip string) http.Transport {
-
-	transport := http.Transport{
-		Dial: (&net.Dialer{
-			Timeout: 5 * time.Second,
-		}).Dial,
-		TLSHandshakeTimeout: 5 * time.Second,
-	}
-
-	dialer := &net.Dialer{
-		Timeout:   30 * time.Second,
-		KeepAlive: 30 * time.Second,
-		DualStack: true,
-	}
-	transport.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
-		if addr == fmt.Sprintf("%s:443", domainName) {
-			addr = fmt.Sprintf("%s:443", ip)
-		} else if ip != "" {
-			addr = fmt.Sprintf("%s:443", ip)
-		}
-
-		return dialer.DialContext(ctx, network, addr)
+// Print the failure of an edge with its category.
+func PrintResponseError(addr *Address, opt *ReqOptions, response *Response) {
+	if addr.getTarget() != response.EdgeIP {
+		fmt.Printf("\n%s - [%s]\n\n", color.HiYellowString(addr.getTarget()), color.HiYellowString(response.EdgeIP))
+	} else {
+		fmt.Printf("\n[%s]\n\n", color.HiYellowString(addr.getTarget()))
 	}
+	PrintFunc("Error", color.HiRedString(response.GetErrorKind()))
+	PrintFunc("Message", response.Error.Error())
+	fmt.Println()
+	printAttempts(response)
+}
 
-	transport.TLSClientConfig = &tls.Config{
-		InsecureSkipVerify: true,
-		MinVersion:         tls.VersionTLS11,
-		MaxVersion:         tls.VersionTLS13,
-	}
+func addRequestHeader(header http.Header, opt *ReqOptions) {
 
-	r := &ReqOptions{
-		Transport: *transport.Clone(),
+	if !opt.getAttackMode() && !opt.getFullBody() {
+		header.Add("Range", "bytes=0-1")
 	}
 
-	return r.getTransport()
-}
-
-func addRequestHeader(req *http.Request, host, referer, authorization string, attack bool) {
-
-	if !attack {
-		req.Header.Add("Range", "bytes=0-1")
+	if opt.getHost() != "" {
+		header.Add("Host", opt.getHost())
 	}
 
-	if host != "" {
-		req.Header.Add("Host", host)
+	if opt.getReferer() != "" {
+		header.Add("Referer", opt.getReferer())
 	}
 
-	if referer != "" {
-		req.Header.Add("Referer", referer)
+	if opt.getAuthorization() != "" {
+		header.Add("Authorization", opt.getAuthorization())
 	}
 
-	if authorization != "" {
-		req.Header.Add("Authorization", authorization)
+	for field, values := range opt.getHeader() {
+		for _, value := range values {
+			header.Add(field, value)
+		}
 	}
 }
 
-func setRequestHeader(resp *http.Response) {
+func setRequestHeader(header http.Header) {
 	req := &ReqOptions{}
 
 	// optional [Host]
-	if len(resp.Request.Header.Values("Host")) > 0 {
-		req.Host = resp.Request.Header.Values("host")[0]
+	if len(header.Values("Host")) > 0 {
+		req.Host = header.Values("host")[0]
 		PrintFunc("Host", req.getHost())
 	}
 
 	// optional [Referer]
-	if len(resp.Request.Header.Values("referer")) > 0 {
-		req.Referer = resp.Request.Header.Values("referer")[0]
+	if len(header.Values("referer")) > 0 {
+		req.Referer = header.Values("referer")[0]
 		PrintFunc("Referer", req.getReferer())
 	}
 
 	// optional [Authorization]
-	if len(resp.Request.Header.Values("Authorization")) > 0 {
-		req.Authorization = resp.Request.Header.Values("Authorization")[0]
+	if len(header.Values("Authorization")) > 0 {
+		req.Authorization = header.Values("Authorization")[0]
 		PrintFunc("Authorization", req.getAuthorization())
 	}
 
 	// required [Range]
-	if len(resp.Request.Header.Values("Range")) > 0 {
-		req.ByteRange = resp.Request.Header.Values("range")[0]
+	if len(header.Values("Range")) > 0 {
+		req.ByteRange = header.Values("range")[0]
 		PrintFunc("Range", req.getRange())
 	}
 	fmt.Println()
 }
 
-func printResponse(resp *http.Response) {
-	for directive, value := range resp.Header {
+func printResponse(header http.Header) {
+	directives := make([]string, 0, len(header))
+	for directive := range header {
+		directives = append(directives, directive)
+	}
+	sort.Strings(directives)
+
+	for _, directive := range directives {
+		value := header[directive]
 		length := len(directive)
 		if length > 14 {
 			word := stringFormat(directive)
@@ -421,138 +400,92 @@ func printResponse(resp *http.Response) {
 	fmt.Println()
 }
 
-func GetStatusCodeOnHTTPS(addr *Address, opt *ReqOptions) *Response {
-
-	response := &Response{}
-	transport := SetTransport(addr.getUrl(), addr.getIP())
-	conn, err := tls.Dial("tcp", fmt.Sprintf("%s:443", addr.getDomainName()), transport.TLSClientConfig)
-	if err != nil {
-		response.Error = err
-		return response
-	}
-	defer conn.Close()
-
-	client := &http.Client{Transport: &transport}
-	url := fmt.Sprintf("https://%s", addr.getUrl())
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		response.Error = err
-		return response
-	}
-
-	var result httpstat.Result
-	ctx := httpstat.WithHTTPStat(req.Context(), &result)
-	req = req.WithContext(ctx)
-	addRequestHeader(req, opt.getHost(), opt.getReferer(), opt.getAuthorization(), opt.getAttackMode())
-
-	latencyTermuiWrapper(url)
-
-	resp, err := client.Do(req)
-	if err != nil {
-		response.Error = err
-		return response
-	}
-	defer resp.Body.Close()
+// Returns the response of the edge using HTTPS protocol and draws its latency on the dashboard.
+func GetStatusCodeOnHTTPS(ctx context.Context, addr *Address, opt *ReqOptions) *Response {
+	latencyTermuiWrapper(ctx, fmt.Sprintf("https://%s", addr.getUrl()), opt)
+	return FetchHTTPS(ctx, addr, opt)
+}
 
-	// Get Contents Hash
-	hasher := sha256.New()
-	if _, err := io.Copy(hasher, resp.Body); err != nil {
-		response.Error = err
-		return response
+// Returns the response of the edge using HTTP protocol and draws its latency on the dashboard.
+func GetStatusCodeOnHTTP(ctx context.Context, addr *Address, opt *ReqOptions) *Response {
+	response := FetchHTTP(ctx, addr, opt)
+	if response.Error == nil {
+		latencyTermuiWrapper(ctx, fmt.Sprintf("http://%s", addr.Url), opt)
 	}
-	sum := hasher.Sum(nil)
-
-	response = &Response{
-		StatusCode:    resp.StatusCode,
-		Server:        resp.Header.Get("Server"),
-		Date:          resp.Header.Get("Date"),
-		LastModified:  resp.Header.Get("Last-Modified"),
-		Etag:          resp.Header.Get("Etag"),
-		Age:           resp.Header.Get("Age"),
-		Expires:       resp.Header.Get("Expires"),
-		CacheControl:  resp.Header.Get("Cache-Control"),
-		ContentType:   resp.Header.Get("Content-Type"),
-		ContentLength: resp.Header.Get("Content-Length"),
-		ACAOrigin:     resp.Header.Get("Access-Control-Allow-Origin"),
-		Via:           resp.Header.Get("Via"),
-		EdgeIP:        addr.getIP(),
-		Hash:          sum,
-		Error:         nil,
-	}
-
 	return response
 }
 
-func GetStatusCodeOnHTTP(addr *Address, opt *ReqOptions) *Response {
-	response := &Response{}
-
-	netURL := url.URL{}
-	ref := fmt.Sprintf("http://%s:%v@%s:%v", addr.getDomainName(), opt.getPort(), addr.getIP(), opt.getPort())
-	urlProxy, err := netURL.Parse(ref)
-	if err != nil {
-		response.Error = err
-		return response
-	}
-
-	client := &http.Client{
-		Transport: &http.Transport{
-			Dial: (&net.Dialer{
-				Timeout: 5 * time.Second,
-			}).Dial,
-			TLSHandshakeTimeout: 5 * time.Second,
-			Proxy:               http.ProxyURL(urlProxy),
-		},
-	}
-
-	urlDomain := fmt.Sprintf("http://%s", addr.Url)
-	req, err := http.NewRequest("GET", urlDomain, nil)
-	if err != nil {
-		response.Error = err
-		return response
-	}
+// Requests the url to the edge using HTTPS protocol without printing anything.
+func FetchHTTPS(ctx context.Context, addr *Address, opt *ReqOptions) *Response {
+	return fetch(ctx, "https", addr, opt)
+}
 
-	var result httpstat.Result
-	ctx := httpstat.WithHTTPStat(req.Context(), &result)
-	req = req.WithContext(ctx)
+// Requests the url to the edge using HTTP protocol without printing anything.
+func FetchHTTP(ctx context.Context, addr *Address, opt *ReqOptions) *Response {
+	return fetch(ctx, "http", addr, opt)
+}
 
-	addRequestHeader(req, opt.getHost(), opt.getReferer(), opt.getAuthorization(), opt.getAttackMode())
+// Probes the edge with the library and turns the result into the response the commands print.
+func fetch(ctx context.Context, protocol string, addr *Address, opt *ReqOptions) *Response {
+	return retry(ctx, opt.Retry, func() *Response {
+		return fetchOnce(ctx, protocol, addr, opt)
+	})
+}
 
-	resp, err := client.Do(req)
+func fetchOnce(ctx context.Context, protocol string, addr *Address, opt *ReqOptions) *Response {
+	result, err := opt.prober().Probe(ctx, newProbeRequest(protocol, addr, opt))
 	if err != nil {
-		response.Error = err
-		return response
-	}
-	defer resp.Body.Close()
-
-	latencyTermuiWrapper(urlDomain)
-
-	// Get Contents Hash
-	hasher := sha256.New()
-	if _, err := io.Copy(hasher, resp.Body); err != nil {
-		response.Error = err
-		return response
-	}
-	sum := hasher.Sum(nil)
-
-	response = &Response{
-		StatusCode:    resp.StatusCode,
-		Server:        resp.Header.Get("Server"),
-		Date:          resp.Header.Get("Date"),
-		LastModified:  resp.Header.Get("Last-Modified"),
-		Etag:          resp.Header.Get("Etag"),
-		Age:           resp.Header.Get("Age"),
-		Expires:       resp.Header.Get("Expires"),
-		CacheControl:  resp.Header.Get("Cache-Control"),
-		ContentType:   resp.Header.Get("Content-Type"),
-		ContentLength: resp.Header.Get("Content-Length"),
-		ACAOrigin:     resp.Header.Get("Access-Control-Allow-Origin"),
-		Via:           resp.Header.Get("Via"),
-		EdgeIP:        addr.getIP(),
-		Hash:          sum,
+		return &Response{EdgeIP: result.EdgeIP, URL: result.URL, Protocol: protocol, Error: err}
+	}
+	return newResponse(protocol, result)
+}
+
+// Returns the request of a probe to the edge of the address with the options.
+func newProbeRequest(protocol string, addr *Address, opt *ReqOptions) gostat.ProbeRequest {
+	request := gostat.ProbeRequest{
+		URL:                fmt.Sprintf("%s://%s", protocol, addr.getUrl()),
+		EdgeIP:             addr.getIP(),
+		Header:             http.Header{},
+		BodyLimit:          opt.getBodyLimit(),
+		DisableCompression: opt.getRawEncoding(),
+	}
+	// The port only applies to http, https is always requested on 443.
+	if protocol == "http" {
+		request.Port = opt.getPort()
+	}
+	addRequestHeader(request.Header, opt)
+	return request
+}
+
+// Returns the response of a successful probe.
+func newResponse(protocol string, result gostat.ProbeResult) *Response {
+	return &Response{
+		StatusCode:    result.StatusCode,
+		Server:        result.Header.Get("Server"),
+		Date:          result.Header.Get("Date"),
+		LastModified:  result.Header.Get("Last-Modified"),
+		Etag:          result.Header.Get("Etag"),
+		Age:           result.Header.Get("Age"),
+		Expires:       result.Header.Get("Expires"),
+		CacheControl:  result.Header.Get("Cache-Control"),
+		ContentType:   result.Header.Get("Content-Type"),
+		ContentLength: result.Header.Get("Content-Length"),
+		ACAOrigin:     result.Header.Get("Access-Control-Allow-Origin"),
+		Via:           result.Header.Get("Via"),
+		EdgeIP:        result.EdgeIP,
+		URL:           result.URL,
+		Hash:          result.Hash,
+		BodySize:      result.BodySize,
+		Body:          result.Body,
+		Status:        result.Status,
+		Protocol:      protocol,
+		Header:        result.Header,
+		RequestHeader: result.RequestHeader,
+		Latency:       result.Timings,
+		Cache:         DecodeCacheStatus(result.Header),
+		TLS:           result.TLS,
 		Error:         nil,
 	}
-
-	return response
 }
 
 func QueryDnsRecord() ([]string, error) {
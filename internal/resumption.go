@@ -0,0 +1,185 @@
+package internal
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/ghdwlsgur/gostat/pkg/gostat"
+)
+
+// Structure with a TLS handshake to an edge.
+type Handshake struct {
+	Duration  time.Duration
+	Resumed   bool
+	ErrorKind string
+}
+
+// Structure with the TLS session resumption of an edge: a full handshake, then a new
+// connection with the session of the first one, then a new connection with the session
+// of another edge.
+type ResumptionResult struct {
+	EdgeIP  string
+	Version string
+	Full    Handshake
+	Resume  Handshake
+	// The edge the session was taken from, empty when there is a single edge.
+	CrossEdge string
+	Cross     Handshake
+}
+
+// Returns the handshake time saved by resuming the session, zero when it was not resumed.
+func (r ResumptionResult) Saved() time.Duration {
+	if !r.Resume.Resumed {
+		return 0
+	}
+	return r.Full.Duration - r.Resume.Duration
+}
+
+// Reports whether a full handshake was made, so that resuming it could be tried.
+func (r ResumptionResult) Handshaked() bool {
+	return r.Full.ErrorKind == ""
+}
+
+// Sends a request to the edge of the address over a new connection, with the session cache.
+func probeHandshake(ctx context.Context, addr *Address, opt *ReqOptions, cache tls.ClientSessionCache) (Handshake, string) {
+	request := newProbeRequest("https", addr, opt)
+	request.TLS.SessionCache = cache
+
+	result, err := opt.prober().Probe(ctx, request)
+	if err != nil {
+		return Handshake{ErrorKind: string(gostat.KindOf(err))}, ""
+	}
+	if result.TLS == nil {
+		return Handshake{ErrorKind: string(gostat.OtherError)}, ""
+	}
+	return Handshake{Duration: result.Timings.TLSHandshake, Resumed: result.TLS.Resumed}, result.TLS.Version
+}
+
+// Makes a full handshake to the edge of the address and keeps its session in the cache.
+func FullHandshake(ctx context.Context, addr *Address, opt *ReqOptions, cache tls.ClientSessionCache) ResumptionResult {
+	full, version := probeHandshake(ctx, addr, opt, cache)
+	return ResumptionResult{EdgeIP: addr.getIP(), Version: version, Full: full}
+}
+
+// Makes a new connection to the edge of the address, resuming the session of the cache.
+func ResumeHandshake(ctx context.Context, addr *Address, opt *ReqOptions, cache tls.ClientSessionCache) Handshake {
+	resume, _ := probeHandshake(ctx, addr, opt, cache)
+	return resume
+}
+
+// Makes a new connection to the edge of the address, resuming the session another edge kept
+// in the cache. The cache of the other edge is left as it was.
+func CrossEdgeHandshake(ctx context.Context, addr *Address, opt *ReqOptions, cache tls.ClientSessionCache) Handshake {
+	request := newProbeRequest("https", addr, opt)
+	cross, _ := probeHandshake(ctx, addr, opt, copySession(cache, sessionKey(request.URL)))
+	return cross
+}
+
+// Returns the key of the sessions of the url in a client session cache, the server name the
+// TLS client sends for it.
+func sessionKey(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return u.Hostname()
+}
+
+// Returns a new cache holding the session of the key in the cache, so that the session the
+// client gets in return replaces it there rather than in the cache it was taken from.
+func copySession(cache tls.ClientSessionCache, key string) tls.ClientSessionCache {
+	copied := tls.NewLRUClientSessionCache(1)
+	if session, ok := cache.Get(key); ok {
+		copied.Put(key, session)
+	}
+	return copied
+}
+
+func handshakeOutcome(h Handshake) string {
+	switch {
+	case h.ErrorKind != "":
+		return color.HiRedString(h.ErrorKind)
+	case h.Resumed:
+		return color.HiGreenString("resumed in %s", h.Duration.Round(time.Microsecond))
+	default:
+		return color.HiRedString("full handshake in %s", h.Duration.Round(time.Microsecond))
+	}
+}
+
+// Prints the full and resumed handshakes of each edge, and whether the edges share their sessions.
+func PrintResumption(url string, results []ResumptionResult) {
+	fmt.Printf("\n%s %s\n", color.HiWhiteString("TLS session resumption of"), color.HiYellowString(url))
+
+	resumed, shared := 0, 0
+	for _, r := range results {
+		fmt.Printf("\n[%s]\n", color.HiYellowString(r.EdgeIP))
+		if !r.Handshaked() {
+			PrintFunc("Full", color.HiRedString(r.Full.ErrorKind))
+			continue
+		}
+
+		PrintFunc("Version", r.Version)
+		PrintFunc("Full", fmt.Sprintf("handshake in %s", r.Full.Duration.Round(time.Microsecond)))
+		PrintFunc("Resume", handshakeOutcome(r.Resume))
+		if r.Resume.Resumed {
+			resumed++
+			PrintFunc("Saved", color.HiMagentaString(r.Saved().Round(time.Microsecond).String()))
+		}
+		if r.CrossEdge != "" {
+			PrintFunc("Cross-edge", fmt.Sprintf("%s with the session of %s", handshakeOutcome(r.Cross), r.CrossEdge))
+			if r.Cross.Resumed {
+				shared++
+			}
+		}
+	}
+
+	fmt.Println()
+	fmt.Printf("%s\n", color.HiWhiteString("%d of %d edges resumed their session", resumed, len(results)))
+	if len(results) > 1 {
+		if shared == len(results) {
+			fmt.Println(color.HiGreenString("Every edge resumed the session of another edge, the session tickets are shared"))
+		} else {
+			fmt.Println(color.HiRedString("%d of %d edges resumed the session of another edge", shared, len(results)))
+		}
+	}
+}
+
+// Structure with a handshake written in json output.
+type HandshakeReport struct {
+	Duration  float64 `json:"duration_ms"`
+	Resumed   bool    `json:"resumed"`
+	ErrorKind string  `json:"error_kind,omitempty"`
+}
+
+// Structure with the TLS session resumption of an edge written in json output.
+type ResumptionReport struct {
+	EdgeIP    string           `json:"edge"`
+	Version   string           `json:"version,omitempty"`
+	Full      HandshakeReport  `json:"full"`
+	Resume    *HandshakeReport `json:"resume,omitempty"`
+	Saved     float64          `json:"saved_ms"`
+	CrossEdge string           `json:"cross_edge,omitempty"`
+	Cross     *HandshakeReport `json:"cross,omitempty"`
+}
+
+func (h Handshake) report() HandshakeReport {
+	return HandshakeReport{Duration: milliseconds(h.Duration), Resumed: h.Resumed, ErrorKind: h.ErrorKind}
+}
+
+// Returns the result as a structure that can be written in json output.
+func (r ResumptionResult) Report() ResumptionReport {
+	report := ResumptionReport{EdgeIP: r.EdgeIP, Version: r.Version, Full: r.Full.report(), Saved: milliseconds(r.Saved())}
+	if r.Handshaked() {
+		resume := r.Resume.report()
+		report.Resume = &resume
+	}
+	if r.CrossEdge != "" {
+		cross := r.Cross.report()
+		report.CrossEdge, report.Cross = r.CrossEdge, &cross
+	}
+	return report
+}
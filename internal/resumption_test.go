@@ -0,0 +1,51 @@
+package internal
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/ghdwlsgur/gostat/pkg/gostat"
+)
+
+// Testing that resuming the session of an edge on another edge that does not share its
+// session ticket keys leaves the session of the first edge resumable.
+func TestCrossEdgeSession(t *testing.T) {
+	newEdge := func() *httptest.Server {
+		return httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	}
+	a, b := newEdge(), newEdge()
+	defer a.Close()
+	defer b.Close()
+
+	const rawURL = "https://example.com/"
+	probe := func(srv *httptest.Server, cache tls.ClientSessionCache) bool {
+		u, _ := url.Parse(srv.URL)
+		var port int
+		fmt.Sscan(u.Port(), &port)
+		result, err := (&gostat.Prober{}).Probe(context.Background(), gostat.ProbeRequest{URL: rawURL, EdgeIP: u.Hostname(), Port: port, TLS: gostat.TLSOptions{SessionCache: cache}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return result.TLS.Resumed
+	}
+
+	cacheA, cacheB := tls.NewLRUClientSessionCache(1), tls.NewLRUClientSessionCache(1)
+	if probe(a, cacheA) || probe(b, cacheB) {
+		t.Fatal("expected a full handshake on each edge")
+	}
+
+	if probe(b, copySession(cacheA, sessionKey(rawURL))) {
+		t.Error("expected a full handshake on an edge with other ticket keys")
+	}
+	if !probe(a, cacheA) {
+		t.Error("expected the session of the edge to resume after it was tried on another edge")
+	}
+	if !probe(a, copySession(cacheA, sessionKey(rawURL))) {
+		t.Error("expected the copied session to resume on its edge")
+	}
+}
@@ -0,0 +1,140 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/ghdwlsgur/gostat/pkg/gostat"
+)
+
+// Policy of retrying the request to an edge, retried Retries more times while the response
+// matches one of the conditions of On, waiting Backoff and then twice as long each time.
+type RetryPolicy struct {
+	Retries int
+	Backoff time.Duration
+	On      []string
+}
+
+// Structure with the outcome of a single request to an edge.
+type Attempt struct {
+	StatusCode int
+	ErrorKind  string
+	Latency    time.Duration
+}
+
+// Parses the retry conditions: a status class such as 5xx, a status code such as 503,
+// timeout, reset or the category of an error such as connect_refused.
+func ParseRetryOn(values []string) ([]string, error) {
+	kinds := map[string]bool{"timeout": true, "reset": true}
+	for _, kind := range []gostat.ErrorKind{
+		gostat.DNSFailure, gostat.ConnectRefused, gostat.ConnectTimeout, gostat.TLSHandshakeFailure,
+		gostat.CertificateInvalid, gostat.HTTPTimeout, gostat.ConnectionReset, gostat.OtherError,
+	} {
+		kinds[string(kind)] = true
+	}
+
+	var conditions []string
+	for _, value := range values {
+		for _, condition := range strings.Split(value, ",") {
+			condition = strings.ToLower(strings.TrimSpace(condition))
+			if condition == "" {
+				continue
+			}
+			if len(condition) == 3 && condition[0] >= '1' && condition[0] <= '5' {
+				if _, err := strconv.Atoi(condition); err == nil || condition[1:] == "xx" {
+					conditions = append(conditions, condition)
+					continue
+				}
+			}
+			if !kinds[condition] {
+				return nil, fmt.Errorf("unknown retry condition %q", condition)
+			}
+			conditions = append(conditions, condition)
+		}
+	}
+	return conditions, nil
+}
+
+// Reports whether the response matches one of the retry conditions.
+func (p RetryPolicy) retryable(r *Response) bool {
+	kind := r.GetErrorKind()
+	for _, condition := range p.On {
+		switch {
+		case r.Error != nil && condition == "timeout":
+			if kind == string(gostat.ConnectTimeout) || kind == string(gostat.HTTPTimeout) {
+				return true
+			}
+		case r.Error != nil && condition == "reset":
+			if kind == string(gostat.ConnectionReset) {
+				return true
+			}
+		case r.Error != nil:
+			if kind == condition {
+				return true
+			}
+		case strings.HasSuffix(condition, "xx"):
+			if strconv.Itoa(r.StatusCode/100) == condition[:1] {
+				return true
+			}
+		default:
+			if strconv.Itoa(r.StatusCode) == condition {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Returns the time to wait before the retry following the attempt, counted from zero.
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	return p.Backoff << attempt
+}
+
+// Requests the edge until the response does not match the retry policy or the retries are
+// exhausted, and returns the last response with every attempt when retries are enabled.
+func retry(ctx context.Context, policy RetryPolicy, request func() *Response) *Response {
+	var attempts []Attempt
+	for attempt := 0; ; attempt++ {
+		start := time.Now()
+		response := request()
+		attempts = append(attempts, Attempt{
+			StatusCode: response.StatusCode,
+			ErrorKind:  response.GetErrorKind(),
+			Latency:    time.Since(start),
+		})
+
+		if attempt >= policy.Retries || !policy.retryable(response) || !sleep(ctx, policy.backoff(attempt)) {
+			if policy.Retries > 0 {
+				response.Attempts = attempts
+			}
+			return response
+		}
+	}
+}
+
+// Returns the outcome of the attempt, the status code or the category of its error.
+func (a Attempt) String() string {
+	outcome := strconv.Itoa(a.StatusCode)
+	if a.ErrorKind != "" {
+		outcome = a.ErrorKind
+	}
+	return fmt.Sprintf("%s %dms", outcome, a.Latency.Milliseconds())
+}
+
+// Prints the attempts of the response when it was retried.
+func printAttempts(response *Response) {
+	if len(response.Attempts) < 2 {
+		return
+	}
+
+	outcomes := make([]string, len(response.Attempts))
+	for i, attempt := range response.Attempts {
+		outcomes[i] = attempt.String()
+	}
+	PrintFunc("Attempts", fmt.Sprintf("%s (%s)", color.HiMagentaString(strconv.Itoa(len(response.Attempts))), strings.Join(outcomes, ", ")))
+	fmt.Println()
+}
@@ -0,0 +1,65 @@
+package internal
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ghdwlsgur/gostat/pkg/gostat"
+)
+
+// Testing the parsing of the retry conditions.
+func TestParseRetryOn(t *testing.T) {
+	conditions, err := ParseRetryOn([]string{"5xx, 404", "timeout,reset", "connect_refused"})
+	if err != nil || len(conditions) != 5 {
+		t.Fatalf("unexpected conditions: %v, %v", conditions, err)
+	}
+
+	for _, invalid := range []string{"6xx", "5ab", "slow"} {
+		if _, err := ParseRetryOn([]string{invalid}); err == nil {
+			t.Errorf("expected an error for %s", invalid)
+		}
+	}
+}
+
+// Testing that every attempt is recorded until the response is not retryable.
+func TestRetry(t *testing.T) {
+	policy := RetryPolicy{Retries: 3, On: []string{"5xx", "reset"}}
+	responses := []*Response{
+		{StatusCode: 503},
+		{Error: &gostat.ProbeError{Kind: gostat.ConnectionReset}},
+		{StatusCode: 200},
+		{StatusCode: 503},
+	}
+
+	calls := 0
+	response := retry(context.Background(), policy, func() *Response {
+		calls++
+		return responses[calls-1]
+	})
+	if calls != 3 || response.StatusCode != 200 || len(response.Attempts) != 3 {
+		t.Fatalf("expected 3 attempts ending with 200, got %d: %+v", calls, response.Attempts)
+	}
+	if response.Attempts[1].ErrorKind != string(gostat.ConnectionReset) || response.Attempts[0].StatusCode != 503 {
+		t.Errorf("unexpected attempts: %+v", response.Attempts)
+	}
+
+	calls = 0
+	policy.Retries = 1
+	response = retry(context.Background(), policy, func() *Response {
+		calls++
+		return &Response{StatusCode: 502}
+	})
+	if calls != 2 || response.StatusCode != 502 {
+		t.Errorf("expected the retries to be exhausted after 2 attempts, got %d", calls)
+	}
+
+	calls = 0
+	policy.Retries = 0
+	response = retry(context.Background(), policy, func() *Response {
+		calls++
+		return &Response{StatusCode: 500}
+	})
+	if calls != 1 || response.Attempts != nil {
+		t.Errorf("expected a single attempt without history, got %d: %+v", calls, response.Attempts)
+	}
+}
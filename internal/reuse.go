@@ -0,0 +1,201 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/ghdwlsgur/gostat/pkg/gostat"
+)
+
+// Structure with a request sent to an edge over the connection kept alive since the first one.
+type ReuseRequest struct {
+	StatusCode int
+	ErrorKind  string
+	Latency    time.Duration
+	// Whether the request went over the connection of the previous one.
+	Reused bool
+	// Whether a new connection resumed the TLS session of the previous one.
+	Resumed bool
+	// Whether the edge asked to close the connection after the response.
+	Closed bool
+}
+
+// Structure with the requests sent in turn to an edge over a single connection.
+type ReuseResult struct {
+	URL      string
+	EdgeIP   string
+	Protocol string
+	Requests []ReuseRequest
+}
+
+// Sends count requests in turn to the edge of the address, keeping the connection alive
+// between them, until the context is done.
+func ProbeReuse(ctx context.Context, protocol string, addr *Address, opt *ReqOptions, count int) ReuseResult {
+	request := newProbeRequest(protocol, addr, opt)
+	result := ReuseResult{URL: request.URL, EdgeIP: addr.getIP(), Protocol: protocol}
+
+	session, err := opt.prober().NewSession(request)
+	if err != nil {
+		result.Requests = append(result.Requests, ReuseRequest{ErrorKind: string(gostat.KindOf(err))})
+		return result
+	}
+	defer session.Close()
+
+	for i := 0; i < count && ctx.Err() == nil; i++ {
+		start := time.Now()
+		probe, err := session.Probe(ctx)
+		result.Requests = append(result.Requests, ReuseRequest{
+			StatusCode: probe.StatusCode,
+			ErrorKind:  string(gostat.KindOf(err)),
+			Latency:    time.Since(start),
+			Reused:     probe.Reused,
+			Resumed:    probe.TLS != nil && probe.TLS.Resumed,
+			Closed:     probe.Closed,
+		})
+	}
+	return result
+}
+
+// Returns the latency of the first request, which opened the connection.
+func (r ReuseResult) First() time.Duration {
+	if len(r.Requests) == 0 {
+		return 0
+	}
+	return r.Requests[0].Latency
+}
+
+// Returns the latency of the requests after the first one on average.
+func (r ReuseResult) Subsequent() time.Duration {
+	if len(r.Requests) < 2 {
+		return 0
+	}
+
+	var total time.Duration
+	for _, request := range r.Requests[1:] {
+		total += request.Latency
+	}
+	return total / time.Duration(len(r.Requests)-1)
+}
+
+// Returns the number of connections opened after the first one, and how many of them
+// resumed the TLS session.
+func (r ReuseResult) Reconnections() (reconnections, resumed int) {
+	for i, request := range r.Requests {
+		if i == 0 || request.Reused || request.ErrorKind != "" {
+			continue
+		}
+		reconnections++
+		if request.Resumed {
+			resumed++
+		}
+	}
+	return reconnections, resumed
+}
+
+// Returns the number of responses after which the edge closed the connection.
+func (r ReuseResult) Closed() int {
+	closed := 0
+	for _, request := range r.Requests {
+		if request.Closed {
+			closed++
+		}
+	}
+	return closed
+}
+
+func (request ReuseRequest) String() string {
+	outcome := fmt.Sprintf("%d", request.StatusCode)
+	if request.ErrorKind != "" {
+		outcome = request.ErrorKind
+	}
+
+	connection := "new"
+	switch {
+	case request.Reused:
+		connection = "reused"
+	case request.Resumed:
+		connection = "resumed"
+	}
+	return fmt.Sprintf("%s %s %s", outcome, request.Latency.Round(time.Microsecond), connection)
+}
+
+// Prints the requests of each edge with the latency of the first against the others,
+// the connections closed by the edge and the TLS sessions resumed.
+func PrintReuse(url string, results []ReuseResult) {
+	fmt.Printf("\n%s %s\n", color.HiWhiteString("Connection reuse of"), color.HiYellowString(url))
+
+	for _, r := range results {
+		fmt.Printf("\n[%s]\n", color.HiYellowString(r.EdgeIP))
+
+		requests := make([]string, len(r.Requests))
+		for i, request := range r.Requests {
+			requests[i] = request.String()
+		}
+		PrintFunc("Requests", strings.Join(requests, ", "))
+		PrintFunc("First", r.First().Round(time.Microsecond).String())
+		PrintFunc("Subsequent", fmt.Sprintf("%s on average", r.Subsequent().Round(time.Microsecond)))
+
+		reconnections, resumed := r.Reconnections()
+		if closed := r.Closed(); closed == 0 && reconnections == 0 {
+			PrintFunc("Connection", color.HiGreenString("kept alive"))
+		} else {
+			PrintFunc("Connection", color.HiRedString("closed by the edge %d times, reconnected %d times", closed, reconnections))
+		}
+		if r.Protocol == "https" && reconnections > 0 {
+			value := color.HiGreenString("%d of %d reconnections resumed the TLS session", resumed, reconnections)
+			if resumed < reconnections {
+				value = color.HiRedString("%d of %d reconnections resumed the TLS session", resumed, reconnections)
+			}
+			PrintFunc("Resumption", value)
+		}
+	}
+	fmt.Println()
+}
+
+// Structure with a request of a connection reuse written in json output.
+type ReuseRequestReport struct {
+	Status    int     `json:"status,omitempty"`
+	ErrorKind string  `json:"error_kind,omitempty"`
+	Latency   float64 `json:"latency_ms"`
+	Reused    bool    `json:"reused"`
+	Resumed   bool    `json:"resumed"`
+	Closed    bool    `json:"closed"`
+}
+
+// Structure with the connection reuse of an edge written in json output.
+type ReuseReport struct {
+	URL           string               `json:"url"`
+	EdgeIP        string               `json:"edge"`
+	First         float64              `json:"first_ms"`
+	Subsequent    float64              `json:"subsequent_ms"`
+	Closed        int                  `json:"closed"`
+	Reconnections int                  `json:"reconnections"`
+	Resumed       int                  `json:"resumed"`
+	Requests      []ReuseRequestReport `json:"requests"`
+}
+
+// Returns the result as a structure that can be written in json output.
+func (r ReuseResult) Report() ReuseReport {
+	report := ReuseReport{
+		URL:        r.URL,
+		EdgeIP:     r.EdgeIP,
+		First:      milliseconds(r.First()),
+		Subsequent: milliseconds(r.Subsequent()),
+		Closed:     r.Closed(),
+	}
+	report.Reconnections, report.Resumed = r.Reconnections()
+	for _, request := range r.Requests {
+		report.Requests = append(report.Requests, ReuseRequestReport{
+			Status:    request.StatusCode,
+			ErrorKind: request.ErrorKind,
+			Latency:   milliseconds(request.Latency),
+			Reused:    request.Reused,
+			Resumed:   request.Resumed,
+			Closed:    request.Closed,
+		})
+	}
+	return report
+}
@@ -0,0 +1,39 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+)
+
+// Testing the connection reuse of an edge that keeps the connection alive and of one that closes it.
+func TestProbeReuse(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/close" {
+			w.Header().Set("Connection", "close")
+		}
+		fmt.Fprint(w, "hello")
+	}))
+	defer srv.Close()
+
+	u, _ := url.Parse(srv.URL)
+	port, _ := strconv.Atoi(u.Port())
+	opt := &ReqOptions{Port: port}
+
+	alive := ProbeReuse(context.Background(), "http", &Address{IP: u.Hostname(), Url: "example.com/"}, opt, 3)
+	if reconnections, _ := alive.Reconnections(); len(alive.Requests) != 3 || reconnections != 0 || alive.Closed() != 0 {
+		t.Errorf("expected a single connection kept alive, got %+v", alive.Requests)
+	}
+	if !alive.Requests[1].Reused || alive.Requests[0].Reused {
+		t.Errorf("expected the requests after the first to reuse its connection, got %+v", alive.Requests)
+	}
+
+	closed := ProbeReuse(context.Background(), "http", &Address{IP: u.Hostname(), Url: "example.com/close"}, opt, 3)
+	if reconnections, _ := closed.Reconnections(); reconnections != 2 || closed.Closed() != 3 {
+		t.Errorf("expected a new connection for every request, got %+v", closed.Requests)
+	}
+}
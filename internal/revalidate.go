@@ -0,0 +1,98 @@
+package internal
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+// Structure with the response to a conditional request made with the validators of the first response.
+type RevalidateResult struct {
+	EdgeIP      string
+	First       *Response
+	Conditional *Response
+	Problems    []string
+}
+
+func (r RevalidateResult) Valid() bool {
+	return len(r.Problems) == 0
+}
+
+// Returns the If-None-Match and If-Modified-Since headers built from the validators of the response.
+func ConditionalHeader(r *Response) http.Header {
+	header := http.Header{}
+	if r.GetEtag() != "" {
+		header.Set("If-None-Match", r.GetEtag())
+	}
+	if r.GetLastModified() != "" {
+		header.Set("If-Modified-Since", r.GetLastModified())
+	}
+	return header
+}
+
+// Checks that the conditional request was answered with a 304 without a body and with the same validators.
+func CheckRevalidation(first, conditional *Response) RevalidateResult {
+	result := RevalidateResult{EdgeIP: first.EdgeIP, First: first, Conditional: conditional}
+
+	switch {
+	case first.Error != nil:
+		result.Problems = append(result.Problems, first.Error.Error())
+		return result
+	case first.GetEtag() == "" && first.GetLastModified() == "":
+		result.Problems = append(result.Problems, "no ETag or Last-Modified to revalidate")
+		return result
+	case conditional == nil:
+		return result
+	case conditional.Error != nil:
+		result.Problems = append(result.Problems, conditional.Error.Error())
+		return result
+	}
+
+	if conditional.StatusCode != http.StatusNotModified {
+		result.Problems = append(result.Problems, fmt.Sprintf("returned %d instead of 304 with a body of %d bytes", conditional.StatusCode, conditional.BodySize))
+	} else if conditional.BodySize > 0 {
+		result.Problems = append(result.Problems, fmt.Sprintf("returned 304 with a body of %d bytes", conditional.BodySize))
+	}
+
+	if conditional.GetEtag() != "" && conditional.GetEtag() != first.GetEtag() {
+		result.Problems = append(result.Problems, fmt.Sprintf("ETag changed from %s to %s", first.GetEtag(), conditional.GetEtag()))
+	}
+	if conditional.GetLastModified() != "" && conditional.GetLastModified() != first.GetLastModified() {
+		result.Problems = append(result.Problems, fmt.Sprintf("Last-Modified changed from %s to %s", first.GetLastModified(), conditional.GetLastModified()))
+	}
+
+	return result
+}
+
+// Prints whether each edge answered the conditional request with a 304.
+func PrintRevalidation(url string, results []RevalidateResult) {
+	fmt.Printf("\n%s %s\n", color.HiWhiteString("Revalidation of"), color.HiYellowString(url))
+
+	failed := 0
+	for _, r := range results {
+		fmt.Printf("\n[%s]\n", color.HiYellowString(r.EdgeIP))
+		if r.First.Error == nil {
+			PrintFunc("ETag", emptyValue(r.First.GetEtag()))
+			PrintFunc("Last-Modified", emptyValue(r.First.GetLastModified()))
+		}
+		if r.Conditional != nil && r.Conditional.Error == nil {
+			PrintFunc("Status", fmt.Sprintf("%s -> %s", r.First.GetStatusCode(), r.Conditional.GetStatusCode()))
+		}
+
+		if r.Valid() {
+			PrintFunc("Result", color.HiGreenString("revalidated with 304"))
+			continue
+		}
+		failed++
+		PrintFunc("Result", color.HiRedString(strings.Join(r.Problems, ", ")))
+	}
+
+	fmt.Println()
+	if failed == 0 {
+		fmt.Println(color.HiGreenString("All %d edges revalidated", len(results)))
+	} else {
+		fmt.Println(color.HiRedString("%d of %d edges did not revalidate", failed, len(results)))
+	}
+}
@@ -0,0 +1,42 @@
+package internal
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// Testing the conditional headers built from the validators of a response.
+func TestConditionalHeader(t *testing.T) {
+	header := ConditionalHeader(&Response{Etag: `"a"`, LastModified: "Tue, 10 Oct 2023 10:00:00 GMT"})
+	if header.Get("If-None-Match") != `"a"` || header.Get("If-Modified-Since") != "Tue, 10 Oct 2023 10:00:00 GMT" {
+		t.Errorf("unexpected conditional header: %v", header)
+	}
+	if header := ConditionalHeader(&Response{}); len(header) != 0 {
+		t.Errorf("expected no conditional header without validators, got %v", header)
+	}
+}
+
+// Testing the outcome of a conditional request against each kind of answer.
+func TestCheckRevalidation(t *testing.T) {
+	first := &Response{EdgeIP: "1.1.1.1", StatusCode: 200, Etag: `"a"`, BodySize: 100}
+
+	tests := []struct {
+		name        string
+		first       *Response
+		conditional *Response
+		problems    []string
+	}{
+		{"304", first, &Response{StatusCode: 304, Etag: `"a"`}, nil},
+		{"304 with a body", first, &Response{StatusCode: 304, BodySize: 10}, []string{"returned 304 with a body of 10 bytes"}},
+		{"200", first, &Response{StatusCode: 200, Etag: `"b"`, BodySize: 100}, []string{"returned 200 instead of 304 with a body of 100 bytes", `ETag changed from "a" to "b"`}},
+		{"no validator", &Response{StatusCode: 200}, nil, []string{"no ETag or Last-Modified to revalidate"}},
+		{"failed conditional", first, &Response{Error: errors.New("refused")}, []string{"refused"}},
+	}
+	for _, test := range tests {
+		result := CheckRevalidation(test.first, test.conditional)
+		if !reflect.DeepEqual(result.Problems, test.problems) || result.Valid() != (test.problems == nil) {
+			t.Errorf("%s: expected %q, got %q", test.name, test.problems, result.Problems)
+		}
+	}
+}
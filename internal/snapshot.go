@@ -0,0 +1,226 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+// Structure with the responses of every edge to each url at a point in time.
+type Snapshot struct {
+	Time time.Time     `json:"time"`
+	URLs []SnapshotURL `json:"urls"`
+}
+
+// Structure with the responses of every edge to a url in a snapshot.
+type SnapshotURL struct {
+	URL    string           `json:"url"`
+	Target string           `json:"target"`
+	Edges  []ResponseReport `json:"edges"`
+}
+
+// Reads a snapshot written in json.
+func ReadSnapshot(path string) (*Snapshot, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot := &Snapshot{}
+	if err := json.Unmarshal(data, snapshot); err != nil {
+		return nil, fmt.Errorf("read snapshot %s: %w", path, err)
+	}
+	return snapshot, nil
+}
+
+// Kinds of change between two snapshots.
+const (
+	SnapshotAdded   = "added"
+	SnapshotRemoved = "removed"
+	SnapshotChanged = "changed"
+)
+
+// Structure with a url or edge added or removed between two snapshots, or a field of an edge
+// that changed. The edge is empty when the url itself was added or removed.
+type SnapshotChange struct {
+	URL    string `json:"url"`
+	EdgeIP string `json:"edge,omitempty"`
+	Change string `json:"change"`
+	Field  string `json:"field,omitempty"`
+	Before string `json:"before,omitempty"`
+	After  string `json:"after,omitempty"`
+}
+
+func (r ResponseReport) status() string {
+	if r.Error != "" {
+		if r.ErrorKind != "" {
+			return r.ErrorKind
+		}
+		return "error"
+	}
+	return strconv.Itoa(r.Status)
+}
+
+func (r ResponseReport) certificate() string {
+	if r.TLS == nil {
+		return ""
+	}
+	return r.TLS.Serial
+}
+
+// Headers that differ between two responses of the same object, in addition to the volatile
+// headers that are not diffed against origin, so they are not diffed between snapshots either.
+var snapshotVolatileHeaders = map[string]bool{
+	"Expires":          true,
+	"Set-Cookie":       true,
+	"X-Request-Id":     true,
+	"X-Correlation-Id": true,
+	"X-Amz-Request-Id": true,
+	"X-Amz-Id-2":       true,
+	"X-Amzn-Requestid": true,
+	"X-Amzn-Trace-Id":  true,
+}
+
+// Returns the names of the headers of both snapshots of an edge that are diffed, without the
+// volatile headers and the ignored ones.
+func snapshotHeaderNames(before, after http.Header, ignore map[string]bool) []string {
+	var names []string
+	for _, name := range headerNames(before, after) {
+		if !snapshotVolatileHeaders[name] && !ignore[name] {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// Diffs the status, hash, size, certificate and non-volatile headers of an edge in both
+// snapshots, only the status when it failed in either of them.
+func diffSnapshotEdge(url string, before, after ResponseReport, ignore map[string]bool) []SnapshotChange {
+	var changes []SnapshotChange
+	add := func(field, a, b string) {
+		if a != b {
+			changes = append(changes, SnapshotChange{URL: url, EdgeIP: after.EdgeIP, Change: SnapshotChanged, Field: field, Before: a, After: b})
+		}
+	}
+
+	add("Status", before.status(), after.status())
+	if before.Error != "" || after.Error != "" {
+		return changes
+	}
+	add("Hash", before.Hash, after.Hash)
+	add("Size", strconv.FormatInt(before.Size, 10), strconv.FormatInt(after.Size, 10))
+	add("Certificate", before.certificate(), after.certificate())
+
+	for _, name := range snapshotHeaderNames(before.Header, after.Header, ignore) {
+		add(name, strings.Join(before.Header.Values(name), ", "), strings.Join(after.Header.Values(name), ", "))
+	}
+	return changes
+}
+
+func diffSnapshotURL(before, after SnapshotURL, ignore map[string]bool) []SnapshotChange {
+	edges := map[string]ResponseReport{}
+	for _, edge := range before.Edges {
+		edges[edge.EdgeIP] = edge
+	}
+
+	var changes []SnapshotChange
+	seen := map[string]bool{}
+	for _, edge := range after.Edges {
+		seen[edge.EdgeIP] = true
+		previous, ok := edges[edge.EdgeIP]
+		if !ok {
+			changes = append(changes, SnapshotChange{URL: after.URL, EdgeIP: edge.EdgeIP, Change: SnapshotAdded})
+			continue
+		}
+		changes = append(changes, diffSnapshotEdge(after.URL, previous, edge, ignore)...)
+	}
+	for _, edge := range before.Edges {
+		if !seen[edge.EdgeIP] {
+			changes = append(changes, SnapshotChange{URL: after.URL, EdgeIP: edge.EdgeIP, Change: SnapshotRemoved})
+		}
+	}
+	return changes
+}
+
+// Returns what changed from one snapshot to the other for each url and each of its edges,
+// in the order of the urls and edges of the later snapshot followed by the removed ones.
+// The ignored headers are not diffed.
+func DiffSnapshots(before, after *Snapshot, ignoreHeaders []string) []SnapshotChange {
+	ignore := map[string]bool{}
+	for _, name := range ignoreHeaders {
+		ignore[http.CanonicalHeaderKey(name)] = true
+	}
+
+	urls := map[string]SnapshotURL{}
+	for _, u := range before.URLs {
+		urls[u.URL] = u
+	}
+
+	var changes []SnapshotChange
+	seen := map[string]bool{}
+	for _, u := range after.URLs {
+		seen[u.URL] = true
+		previous, ok := urls[u.URL]
+		if !ok {
+			changes = append(changes, SnapshotChange{URL: u.URL, Change: SnapshotAdded})
+			continue
+		}
+		changes = append(changes, diffSnapshotURL(previous, u, ignore)...)
+	}
+	for _, u := range before.URLs {
+		if !seen[u.URL] {
+			changes = append(changes, SnapshotChange{URL: u.URL, Change: SnapshotRemoved})
+		}
+	}
+	return changes
+}
+
+// Prints the changes grouped by url and edge.
+func PrintSnapshotDiff(before, after *Snapshot, changes []SnapshotChange) {
+	fmt.Printf("\n%s %s %s %s\n",
+		color.HiWhiteString("Diff of"),
+		color.HiYellowString(before.Time.Local().Format(time.RFC3339)),
+		color.HiWhiteString("and"),
+		color.HiYellowString(after.Time.Local().Format(time.RFC3339)),
+	)
+	if len(changes) == 0 {
+		fmt.Printf("\n%s\n", color.HiGreenString("No change"))
+		return
+	}
+
+	url, edge := "", ""
+	for _, c := range changes {
+		if c.URL != url {
+			url, edge = c.URL, ""
+			fmt.Printf("\n%s\n", color.HiWhiteString(url))
+		}
+
+		switch {
+		case c.EdgeIP == "" && c.Change == SnapshotAdded:
+			fmt.Println(color.HiGreenString("added"))
+		case c.EdgeIP == "":
+			fmt.Println(color.HiRedString("removed"))
+		case c.Change == SnapshotAdded:
+			fmt.Printf("[%s] %s\n", color.HiYellowString(c.EdgeIP), color.HiGreenString("added"))
+		case c.Change == SnapshotRemoved:
+			fmt.Printf("[%s] %s\n", color.HiYellowString(c.EdgeIP), color.HiRedString("removed"))
+		default:
+			if c.EdgeIP != edge {
+				edge = c.EdgeIP
+				fmt.Printf("[%s]\n", color.HiYellowString(edge))
+			}
+			field := c.Field
+			if len(field) > 14 {
+				field = stringFormat(field)
+			}
+			PrintFunc(field, fmt.Sprintf("%s -> %s", color.HiRedString(emptyValue(c.Before)), color.HiGreenString(emptyValue(c.After))))
+		}
+	}
+	fmt.Println()
+}
@@ -0,0 +1,74 @@
+package internal
+
+import (
+	"net/http"
+	"testing"
+)
+
+// Testing the changes of urls, edges and fields between two snapshots.
+func TestDiffSnapshots(t *testing.T) {
+	before := &Snapshot{URLs: []SnapshotURL{
+		{URL: "https://example.com/", Edges: []ResponseReport{
+			{EdgeIP: "1.1.1.1", Status: 200, Hash: "a", Header: http.Header{"Etag": {`"1"`}, "Date": {"Mon"}}, TLS: &TLSInfo{Serial: "1"}},
+			{EdgeIP: "1.1.1.2", Status: 200, Hash: "a"},
+			{EdgeIP: "1.1.1.3", Status: 200, Hash: "a"},
+		}},
+		{URL: "https://example.com/old"},
+	}}
+	after := &Snapshot{URLs: []SnapshotURL{
+		{URL: "https://example.com/", Edges: []ResponseReport{
+			{EdgeIP: "1.1.1.1", Status: 200, Hash: "b", Header: http.Header{"Etag": {`"2"`}, "Date": {"Tue"}}, TLS: &TLSInfo{Serial: "2"}},
+			{EdgeIP: "1.1.1.2", Error: "connect_timeout: dial", ErrorKind: "connect_timeout"},
+			{EdgeIP: "1.1.1.4", Status: 200, Hash: "b"},
+		}},
+		{URL: "https://example.com/new"},
+	}}
+
+	expected := []SnapshotChange{
+		{URL: "https://example.com/", EdgeIP: "1.1.1.1", Change: SnapshotChanged, Field: "Hash", Before: "a", After: "b"},
+		{URL: "https://example.com/", EdgeIP: "1.1.1.1", Change: SnapshotChanged, Field: "Certificate", Before: "1", After: "2"},
+		{URL: "https://example.com/", EdgeIP: "1.1.1.1", Change: SnapshotChanged, Field: "Etag", Before: `"1"`, After: `"2"`},
+		{URL: "https://example.com/", EdgeIP: "1.1.1.2", Change: SnapshotChanged, Field: "Status", Before: "200", After: "connect_timeout"},
+		{URL: "https://example.com/", EdgeIP: "1.1.1.4", Change: SnapshotAdded},
+		{URL: "https://example.com/", EdgeIP: "1.1.1.3", Change: SnapshotRemoved},
+		{URL: "https://example.com/new", Change: SnapshotAdded},
+		{URL: "https://example.com/old", Change: SnapshotRemoved},
+	}
+
+	changes := DiffSnapshots(before, after, nil)
+	if len(changes) != len(expected) {
+		t.Fatalf("expected %d changes, got %+v", len(expected), changes)
+	}
+	for i, c := range expected {
+		if changes[i] != c {
+			t.Errorf("expected %+v, got %+v", c, changes[i])
+		}
+	}
+
+	if changes := DiffSnapshots(after, after, nil); len(changes) != 0 {
+		t.Errorf("expected no change, got %+v", changes)
+	}
+}
+
+// Testing that the headers that change on every response and the ignored ones are not diffed.
+func TestDiffSnapshotsIgnoredHeaders(t *testing.T) {
+	snapshot := func(value string) *Snapshot {
+		header := http.Header{
+			"Expires":       {value},
+			"Set-Cookie":    {"id=" + value},
+			"X-Request-Id":  {value},
+			"X-Version":     {value},
+			"Cache-Control": {"max-age=60"},
+		}
+		return &Snapshot{URLs: []SnapshotURL{{URL: "https://example.com/", Edges: []ResponseReport{{EdgeIP: "1.1.1.1", Status: 200, Header: header}}}}}
+	}
+	before, after := snapshot("1"), snapshot("2")
+
+	changes := DiffSnapshots(before, after, nil)
+	if len(changes) != 1 || changes[0].Field != "X-Version" {
+		t.Errorf("expected a change of X-Version only, got %+v", changes)
+	}
+	if changes := DiffSnapshots(before, after, []string{"x-version"}); len(changes) != 0 {
+		t.Errorf("expected no change with X-Version ignored, got %+v", changes)
+	}
+}
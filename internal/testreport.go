@@ -0,0 +1,124 @@
+package internal
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Time     string           `xml:"time,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name     string          `xml:"name,attr"`
+	Tests    int             `xml:"tests,attr"`
+	Failures int             `xml:"failures,attr"`
+	Time     string          `xml:"time,attr"`
+	Cases    []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	Classname string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+func seconds(d time.Duration) string {
+	return fmt.Sprintf("%.3f", d.Seconds())
+}
+
+// Writes the check results as JUnit XML, a test suite per url and a test case per edge and assertion.
+// Every assertion of an edge is checked on the same response, so the latency of the edge is
+// given to its first test case only and counted once in the time of the suite.
+func WriteJUnit(w io.Writer, results []CheckResult) error {
+	report := junitTestSuites{Name: "gostat"}
+	index := map[string]int{}
+	timed := map[[2]string]bool{}
+	var total time.Duration
+	suiteTime := map[string]time.Duration{}
+
+	for _, r := range results {
+		i, ok := index[r.URL]
+		if !ok {
+			i = len(report.Suites)
+			index[r.URL] = i
+			report.Suites = append(report.Suites, junitTestSuite{Name: r.URL})
+		}
+		suite := &report.Suites[i]
+
+		var elapsed time.Duration
+		if edge := [2]string{r.URL, r.EdgeIP}; !timed[edge] {
+			timed[edge] = true
+			elapsed = r.Elapsed
+		}
+
+		testCase := junitTestCase{
+			Name:      fmt.Sprintf("[%s] %s", r.EdgeIP, r.Name),
+			Classname: r.URL,
+			Time:      seconds(elapsed),
+		}
+		if r.Passed {
+			testCase.SystemOut = r.Message
+		} else {
+			testCase.Failure = &junitFailure{Message: r.Message, Type: r.Name, Text: r.Message}
+			suite.Failures++
+			report.Failures++
+		}
+
+		suite.Cases = append(suite.Cases, testCase)
+		suite.Tests++
+		report.Tests++
+		suiteTime[r.URL] += elapsed
+		total += elapsed
+	}
+
+	for i := range report.Suites {
+		report.Suites[i].Time = seconds(suiteTime[report.Suites[i].Name])
+	}
+	report.Time = seconds(total)
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(report); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// Writes the check results in the Test Anything Protocol, a test point per edge and assertion.
+func WriteTAP(w io.Writer, results []CheckResult) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "TAP version 13\n1..%d\n", len(results))
+
+	for i, r := range results {
+		status := "ok"
+		if !r.Passed {
+			status = "not ok"
+		}
+		fmt.Fprintf(&b, "%s %d - %s [%s] %s\n", status, i+1, r.URL, r.EdgeIP, r.Name)
+		fmt.Fprintf(&b, "  ---\n  message: %q\n  duration_ms: %.3f\n  ...\n", r.Message, milliseconds(r.Elapsed))
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,73 @@
+package internal
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+var reportResults = []CheckResult{
+	{URL: "https://example.com/", EdgeIP: "1.1.1.1", Name: "status", Passed: true, Message: "expected 200, got 200", Elapsed: 120 * time.Millisecond},
+	{URL: "https://example.com/", EdgeIP: "1.1.1.2", Name: "status", Passed: false, Message: "expected 200, got 503", Elapsed: 80 * time.Millisecond},
+}
+
+// Testing the JUnit XML report of check results.
+func TestWriteJUnit(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteJUnit(&buf, reportResults); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, expected := range []string{
+		`<testsuites name="gostat" tests="2" failures="1" time="0.200">`,
+		`<testsuite name="https://example.com/" tests="2" failures="1" time="0.200">`,
+		`<testcase name="[1.1.1.2] status" classname="https://example.com/" time="0.080">`,
+		`<failure message="expected 200, got 503" type="status">expected 200, got 503</failure>`,
+	} {
+		if !strings.Contains(buf.String(), expected) {
+			t.Errorf("expected %s in:\n%s", expected, buf.String())
+		}
+	}
+}
+
+// Testing that the latency of an edge is counted once however many assertions it has.
+func TestWriteJUnitTime(t *testing.T) {
+	results := append([]CheckResult{
+		{URL: "https://example.com/", EdgeIP: "1.1.1.1", Name: "header", Passed: true, Elapsed: 120 * time.Millisecond},
+	}, reportResults...)
+
+	var buf bytes.Buffer
+	if err := WriteJUnit(&buf, results); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, expected := range []string{
+		`<testsuites name="gostat" tests="3" failures="1" time="0.200">`,
+		`<testcase name="[1.1.1.1] header" classname="https://example.com/" time="0.120">`,
+		`<testcase name="[1.1.1.1] status" classname="https://example.com/" time="0.000">`,
+		`<testcase name="[1.1.1.2] status" classname="https://example.com/" time="0.080">`,
+	} {
+		if !strings.Contains(buf.String(), expected) {
+			t.Errorf("expected %s in:\n%s", expected, buf.String())
+		}
+	}
+}
+
+// Testing the TAP report of check results.
+func TestWriteTAP(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteTAP(&buf, reportResults); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, expected := range []string{
+		"TAP version 13\n1..2\n",
+		"ok 1 - https://example.com/ [1.1.1.1] status\n",
+		"not ok 2 - https://example.com/ [1.1.1.2] status\n  ---\n  message: \"expected 200, got 503\"\n  duration_ms: 80.000\n  ...\n",
+	} {
+		if !strings.Contains(buf.String(), expected) {
+			t.Errorf("expected %q in:\n%s", expected, buf.String())
+		}
+	}
+}
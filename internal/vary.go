@@ -0,0 +1,144 @@
+package internal
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+// A variation of the request sent to the same edge to probe its cache key.
+type Variation struct {
+	Name   string
+	Header http.Header
+	Query  string
+}
+
+// Structure with the response to a variation compared to the warmed baseline.
+type VariationResult struct {
+	Variation Variation
+	Response  *Response
+	Distinct  bool
+	Reasons   []string
+}
+
+func headerVariation(name, field, value string) Variation {
+	return Variation{Name: name, Header: http.Header{field: []string{value}}}
+}
+
+// Accept-Encoding of the baseline request when none is given, the one the Go client sends by
+// default. The baseline is fetched with the raw encoding so that its hash is taken on the bytes
+// the edge sent, like the hashes of the encoding variations.
+const BaselineEncoding = "gzip"
+
+// Returns the header classes and query string permutations varied against the baseline request.
+func DefaultVariations(url string) []Variation {
+	variations := []Variation{
+		headerVariation("Accept-Encoding: identity", "Accept-Encoding", "identity"),
+		headerVariation("Accept-Encoding: br", "Accept-Encoding", "br"),
+		// A list of codings like the one of browsers, which the baseline only shares when the
+		// edge normalizes Accept-Encoding in its cache key.
+		headerVariation("Accept-Encoding: browser", "Accept-Encoding", "gzip, deflate, br, zstd"),
+		headerVariation("Accept-Language: en-US", "Accept-Language", "en-US,en;q=0.9"),
+		headerVariation("Accept-Language: ko-KR", "Accept-Language", "ko-KR,ko;q=0.9"),
+		headerVariation("User-Agent: desktop", "User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/118.0.0.0 Safari/537.36"),
+		headerVariation("User-Agent: mobile", "User-Agent", "Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.0 Mobile/15E148 Safari/604.1"),
+		headerVariation("User-Agent: bot", "User-Agent", "Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)"),
+		headerVariation("Cookie: session", "Cookie", "session=gostat-probe"),
+		{Name: "Query: unknown parameter", Query: addQuery(url, "gostat=1")},
+		{Name: "Query: tracking parameter", Query: addQuery(url, "utm_source=gostat")},
+	}
+
+	if reordered, ok := reverseQuery(url); ok {
+		variations = append(variations, Variation{Name: "Query: reordered parameters", Query: reordered})
+	}
+
+	return variations
+}
+
+func addQuery(url, param string) string {
+	if strings.Contains(url, "?") {
+		return url + "&" + param
+	}
+	return url + "?" + param
+}
+
+// Returns the url with its query parameters in reverse order, when it has more than one.
+func reverseQuery(url string) (string, bool) {
+	i := strings.Index(url, "?")
+	if i < 0 {
+		return "", false
+	}
+
+	params := strings.Split(url[i+1:], "&")
+	if len(params) < 2 {
+		return "", false
+	}
+	for l, r := 0, len(params)-1; l < r; l, r = l+1, r-1 {
+		params[l], params[r] = params[r], params[l]
+	}
+	return url[:i+1] + strings.Join(params, "&"), true
+}
+
+// Compares the response to a variation with the baseline, a variation served from
+// a different cached object differs in hash or ETag, or misses while the baseline hits.
+// The hashes are not compared when the content encodings differ, since the same object
+// encoded differently never has the same hash.
+func CompareVariation(baseline *Response, variation Variation, response *Response) VariationResult {
+	result := VariationResult{Variation: variation, Response: response}
+	if response.Error != nil {
+		return result
+	}
+
+	sameEncoding := contentEncoding(response) == contentEncoding(baseline)
+	if sameEncoding && response.GetHash() != baseline.GetHash() {
+		result.Reasons = append(result.Reasons, "Hash")
+	}
+	if response.GetEtag() != baseline.GetEtag() {
+		result.Reasons = append(result.Reasons, "ETag")
+	}
+	if baseline.Cache.Status == CacheHit && response.Cache.Status != CacheHit && response.Cache.Status != CacheUnknown {
+		result.Reasons = append(result.Reasons, "Cache "+string(response.Cache.Status))
+	}
+	result.Distinct = len(result.Reasons) > 0
+
+	return result
+}
+
+func contentEncoding(r *Response) string {
+	if encoding := r.Header.Get("Content-Encoding"); encoding != "" {
+		return strings.ToLower(encoding)
+	}
+	return "identity"
+}
+
+// Prints which variations are served from a cached object distinct from the baseline.
+func PrintVariations(url, edgeIP string, baseline *Response, results []VariationResult) {
+	fmt.Printf("\n%s %s - [%s]\n\n", color.HiWhiteString("Cache key of"), color.HiYellowString(url), color.HiYellowString(edgeIP))
+	if baseline.Error != nil {
+		PrintFunc("Error", color.HiRedString(baseline.Error.Error()))
+		return
+	}
+	PrintFunc("Baseline", fmt.Sprintf("%s  %s  %s", baseline.GetStatusCode(), baseline.GetCacheStatus(), baseline.GetHash()))
+	if vary := baseline.Header.Get("Vary"); vary != "" {
+		PrintFunc("Vary", vary)
+	}
+	fmt.Println()
+
+	distinct := 0
+	for _, r := range results {
+		switch {
+		case r.Response.Error != nil:
+			fmt.Printf("%s\n\t\t%s\n", r.Variation.Name, color.HiRedString("error  %s", r.Response.Error.Error()))
+		case r.Distinct:
+			distinct++
+			fmt.Printf("%s\n\t\t%s\n", r.Variation.Name, color.HiYellowString("distinct cache object (%s)", strings.Join(r.Reasons, ", ")))
+		default:
+			fmt.Printf("%s\n\t\t%s\n", r.Variation.Name, color.HiGreenString("shares the cached object (%s)", r.Response.GetCacheStatus()))
+		}
+	}
+
+	fmt.Println()
+	fmt.Println(color.HiWhiteString("%d of %d variations produce a distinct cached object", distinct, len(results)))
+}
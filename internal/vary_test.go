@@ -0,0 +1,61 @@
+package internal
+
+import (
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+// Testing the reasons a variation is served from a distinct cached object.
+func TestCompareVariation(t *testing.T) {
+	gzipped := http.Header{"Content-Encoding": {"gzip"}}
+	baseline := &Response{Hash: []byte("gzip"), Etag: `"a"`, Header: gzipped, Cache: CacheInfo{Status: CacheHit}}
+	variation := Variation{Name: "test"}
+
+	tests := []struct {
+		name     string
+		response *Response
+		reasons  []string
+	}{
+		{"shared", &Response{Hash: []byte("gzip"), Etag: `"a"`, Header: gzipped, Cache: CacheInfo{Status: CacheHit}}, nil},
+		{"hash", &Response{Hash: []byte("other"), Etag: `"a"`, Header: gzipped, Cache: CacheInfo{Status: CacheHit}}, []string{"Hash"}},
+		{"etag and miss", &Response{Hash: []byte("gzip"), Etag: `"b"`, Header: gzipped, Cache: CacheInfo{Status: CacheMiss}}, []string{"ETag", "Cache MISS"}},
+		{"unknown cache status", &Response{Hash: []byte("gzip"), Etag: `"a"`, Header: gzipped, Cache: CacheInfo{Status: CacheUnknown}}, nil},
+		{"other encoding of the same object", &Response{Hash: []byte("identity"), Etag: `"a"`, Header: http.Header{}, Cache: CacheInfo{Status: CacheHit}}, nil},
+		{"other encoding of another object", &Response{Hash: []byte("br"), Etag: `"b"`, Header: http.Header{"Content-Encoding": {"br"}}, Cache: CacheInfo{Status: CacheHit}}, []string{"ETag"}},
+	}
+	for _, test := range tests {
+		result := CompareVariation(baseline, variation, test.response)
+		if !reflect.DeepEqual(result.Reasons, test.reasons) || result.Distinct != (test.reasons != nil) {
+			t.Errorf("%s: expected %v, got %v (distinct %v)", test.name, test.reasons, result.Reasons, result.Distinct)
+		}
+	}
+
+	if result := CompareVariation(baseline, variation, &Response{Error: errors.New("refused")}); result.Distinct {
+		t.Errorf("expected a failed variation not to be distinct, got %+v", result)
+	}
+}
+
+// Testing the query strings added to and reordered in the url.
+func TestVariationQuery(t *testing.T) {
+	if url := addQuery("https://a.com/", "gostat=1"); url != "https://a.com/?gostat=1" {
+		t.Errorf("unexpected url: %s", url)
+	}
+	if url := addQuery("https://a.com/?a=1", "gostat=1"); url != "https://a.com/?a=1&gostat=1" {
+		t.Errorf("unexpected url: %s", url)
+	}
+
+	if url, ok := reverseQuery("https://a.com/?a=1&b=2&c=3"); !ok || url != "https://a.com/?c=3&b=2&a=1" {
+		t.Errorf("unexpected reordered url: %s, %v", url, ok)
+	}
+	for _, url := range []string{"https://a.com/", "https://a.com/?a=1"} {
+		if _, ok := reverseQuery(url); ok {
+			t.Errorf("expected %s not to be reordered", url)
+		}
+	}
+
+	if n := len(DefaultVariations("https://a.com/?a=1&b=2")); n != len(DefaultVariations("https://a.com/"))+1 {
+		t.Errorf("expected a reordered variation with several parameters, got %d variations", n)
+	}
+}
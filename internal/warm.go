@@ -0,0 +1,110 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+// A single request made while warming an edge.
+type WarmAttempt struct {
+	StatusCode int
+	Cache      CacheStatus
+	Age        string
+	Error      error
+}
+
+// Structure with the warm-up history of an edge.
+type WarmResult struct {
+	EdgeIP   string
+	Warm     bool
+	Attempts []WarmAttempt
+	Elapsed  time.Duration
+}
+
+// Requests the edge until its cache status is HIT, the attempts run out or the context is done,
+// waiting interval between requests.
+func WarmEdge(ctx context.Context, edgeIP string, fetch func() *Response, maxAttempts int, interval time.Duration) WarmResult {
+	result := WarmResult{EdgeIP: edgeIP}
+	start := time.Now()
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+
+	for i := 0; i < maxAttempts; i++ {
+		if i > 0 && !sleep(ctx, interval) {
+			break
+		}
+
+		response := fetch()
+		result.Attempts = append(result.Attempts, WarmAttempt{
+			StatusCode: response.StatusCode,
+			Cache:      response.Cache.Status,
+			Age:        response.GetAge(),
+			Error:      response.Error,
+		})
+
+		if response.Error == nil && response.Cache.Status == CacheHit {
+			result.Warm = true
+			break
+		}
+	}
+	result.Elapsed = time.Since(start)
+
+	return result
+}
+
+// Prints how many requests and how long it took for each edge to become warm.
+func PrintWarmResults(url string, results []WarmResult) {
+	fmt.Printf("\n%s %s\n", color.HiWhiteString("Warm-up of"), color.HiYellowString(url))
+
+	cold := 0
+	for _, r := range results {
+		fmt.Printf("\n[%s]\n", color.HiYellowString(r.EdgeIP))
+		if r.Warm {
+			PrintFunc("Result", color.HiGreenString("warm after %d requests in %s", len(r.Attempts), r.Elapsed.Round(time.Millisecond)))
+		} else {
+			cold++
+			PrintFunc("Result", color.HiRedString("not warm after %d requests in %s", len(r.Attempts), r.Elapsed.Round(time.Millisecond)))
+		}
+
+		var caches, ages []string
+		for _, a := range r.Attempts {
+			if a.Error != nil {
+				caches = append(caches, "ERROR")
+				ages = append(ages, "-")
+				continue
+			}
+			caches = append(caches, emptyValue(string(a.Cache)))
+			ages = append(ages, emptyValue(a.Age))
+		}
+		PrintFunc("Cache", strings.Join(caches, " -> "))
+		PrintFunc("Age", strings.Join(ages, " -> "))
+		if last := r.Attempts[len(r.Attempts)-1]; last.Error != nil {
+			PrintFunc("Error", color.HiRedString(last.Error.Error()))
+		}
+	}
+
+	fmt.Println()
+	if cold == 0 {
+		fmt.Println(color.HiGreenString("All %d edges are warm", len(results)))
+	} else {
+		fmt.Println(color.HiRedString("%d of %d edges are not warm", cold, len(results)))
+	}
+}
+
+// Waits for the duration and reports whether the context was still running by then.
+func sleep(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
@@ -0,0 +1,61 @@
+package internal
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// Returns a fetch that answers with the cache statuses in turn, repeating the last one.
+func fakeWarmFetch(statuses ...CacheStatus) (func() *Response, *int) {
+	calls := 0
+	return func() *Response {
+		status := statuses[len(statuses)-1]
+		if calls < len(statuses) {
+			status = statuses[calls]
+		}
+		calls++
+		return &Response{StatusCode: 200, Cache: CacheInfo{Status: status}}
+	}, &calls
+}
+
+// Testing that an edge is warm once it answers HIT after a MISS.
+func TestWarmEdgeHit(t *testing.T) {
+	fetch, calls := fakeWarmFetch(CacheMiss, CacheMiss, CacheHit)
+	result := WarmEdge(context.Background(), "1.1.1.1", fetch, 5, time.Millisecond)
+
+	if !result.Warm || len(result.Attempts) != 3 || *calls != 3 {
+		t.Errorf("expected warm after 3 attempts, got %+v after %d calls", result, *calls)
+	}
+	if result.Attempts[0].Cache != CacheMiss || result.Attempts[2].Cache != CacheHit {
+		t.Errorf("unexpected attempts: %+v", result.Attempts)
+	}
+}
+
+// Testing that warming stops once the attempts run out.
+func TestWarmEdgeExhausted(t *testing.T) {
+	fetch, calls := fakeWarmFetch(CacheMiss)
+	result := WarmEdge(context.Background(), "1.1.1.1", fetch, 3, time.Millisecond)
+
+	if result.Warm || len(result.Attempts) != 3 || *calls != 3 {
+		t.Errorf("expected cold after 3 attempts, got %+v after %d calls", result, *calls)
+	}
+}
+
+// Testing that warming stops without waiting the interval once the context is done.
+func TestWarmEdgeCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	fetch, calls := fakeWarmFetch(CacheMiss)
+	canceling := func() *Response {
+		cancel()
+		return fetch()
+	}
+
+	result := WarmEdge(ctx, "1.1.1.1", canceling, 5, time.Hour)
+	if result.Warm || len(result.Attempts) != 1 || *calls != 1 {
+		t.Errorf("expected a single attempt, got %+v after %d calls", result, *calls)
+	}
+	if result.Elapsed >= time.Hour {
+		t.Errorf("expected the interval to be skipped, took %s", result.Elapsed)
+	}
+}
@@ -0,0 +1,59 @@
+package gostat
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"math"
+	"time"
+)
+
+// Structure with the negotiated TLS connection and the leaf certificate of the edge.
+type TLSInfo struct {
+	Version     string    `json:"version"`
+	CipherSuite string    `json:"cipher_suite"`
+	Subject     string    `json:"subject"`
+	Issuer      string    `json:"issuer"`
+	Serial      string    `json:"serial"`
+	DNSNames    []string  `json:"dns_names,omitempty"`
+	NotBefore   time.Time `json:"not_before"`
+	NotAfter    time.Time `json:"not_after"`
+	VerifyError string    `json:"verify_error,omitempty"`
+	// Whether the handshake resumed a previous session instead of a full handshake.
+	Resumed bool `json:"resumed,omitempty"`
+}
+
+// Returns the number of whole days left until the certificate expires.
+func (t *TLSInfo) DaysLeft() int {
+	return int(math.Floor(time.Until(t.NotAfter).Hours() / 24))
+}
+
+// Returns the TLS information of the connection, verifying the chain against the roots
+// for the server name because the transport itself skips verification to reach each edge.
+func newTLSInfo(state *tls.ConnectionState, serverName string, roots *x509.CertPool) *TLSInfo {
+	if state == nil || len(state.PeerCertificates) == 0 {
+		return nil
+	}
+
+	leaf := state.PeerCertificates[0]
+	info := &TLSInfo{
+		Version:     tls.VersionName(state.Version),
+		CipherSuite: tls.CipherSuiteName(state.CipherSuite),
+		Subject:     leaf.Subject.CommonName,
+		Issuer:      leaf.Issuer.CommonName,
+		Serial:      leaf.SerialNumber.String(),
+		DNSNames:    leaf.DNSNames,
+		NotBefore:   leaf.NotBefore,
+		NotAfter:    leaf.NotAfter,
+		Resumed:     state.DidResume,
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, cert := range state.PeerCertificates[1:] {
+		intermediates.AddCert(cert)
+	}
+	if _, err := leaf.Verify(x509.VerifyOptions{DNSName: serverName, Roots: roots, Intermediates: intermediates}); err != nil {
+		info.VerifyError = err.Error()
+	}
+
+	return info
+}
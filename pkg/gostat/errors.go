@@ -0,0 +1,166 @@
+package gostat
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http/httptrace"
+	"sync"
+	"syscall"
+)
+
+// Category of the failure of a probe.
+type ErrorKind string
+
+const (
+	DNSFailure          ErrorKind = "dns_failure"
+	ConnectRefused      ErrorKind = "connect_refused"
+	ConnectTimeout      ErrorKind = "connect_timeout"
+	TLSHandshakeFailure ErrorKind = "tls_handshake_failure"
+	CertificateInvalid  ErrorKind = "cert_invalid"
+	HTTPTimeout         ErrorKind = "http_timeout"
+	ConnectionReset     ErrorKind = "connection_reset"
+	Canceled            ErrorKind = "canceled"
+	OtherError          ErrorKind = "error"
+)
+
+// Error returned by a probe, with the category of the failure.
+type ProbeError struct {
+	Kind ErrorKind
+	Err  error
+}
+
+func (e *ProbeError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Kind, e.Err)
+}
+
+func (e *ProbeError) Unwrap() error {
+	return e.Err
+}
+
+// Returns the category of the error, taken from the ProbeError it wraps when there is one,
+// otherwise guessed from the error alone.
+func KindOf(err error) ErrorKind {
+	var probeErr *ProbeError
+	if errors.As(err, &probeErr) {
+		return probeErr.Kind
+	}
+	return Classify(err)
+}
+
+// Guesses the category of an error without knowing how far the request went.
+func Classify(err error) ErrorKind {
+	var dnsErr *net.DNSError
+	var opErr *net.OpError
+	var recordErr tls.RecordHeaderError
+
+	switch {
+	case err == nil:
+		return ""
+	case errors.Is(err, context.Canceled):
+		return Canceled
+	case errors.As(err, &dnsErr):
+		return DNSFailure
+	case isCertificateError(err):
+		return CertificateInvalid
+	case errors.As(err, &recordErr):
+		return TLSHandshakeFailure
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return ConnectRefused
+	case isReset(err):
+		return ConnectionReset
+	case isTimeout(err):
+		if errors.As(err, &opErr) && opErr.Op == "dial" {
+			return ConnectTimeout
+		}
+		return HTTPTimeout
+	}
+	return OtherError
+}
+
+func isCertificateError(err error) bool {
+	var unknownAuthority x509.UnknownAuthorityError
+	var hostname x509.HostnameError
+	var invalid x509.CertificateInvalidError
+	return errors.As(err, &unknownAuthority) || errors.As(err, &hostname) || errors.As(err, &invalid)
+}
+
+func isReset(err error) bool {
+	return errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.EPIPE) ||
+		errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+func isTimeout(err error) bool {
+	var netErr net.Error
+	return errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout())
+}
+
+// Records how far a request went so that its failure can be categorized.
+type probeTrace struct {
+	mu         sync.Mutex
+	connected  bool
+	reused     bool
+	dnsErr     error
+	connectErr error
+	tlsErr     error
+}
+
+func (t *probeTrace) clientTrace() *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		DNSDone: func(info httptrace.DNSDoneInfo) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.dnsErr = info.Err
+		},
+		ConnectDone: func(network, addr string, err error) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.connectErr = err
+		},
+		TLSHandshakeDone: func(state tls.ConnectionState, err error) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.tlsErr = err
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.connected = true
+			t.reused = info.Reused
+		},
+	}
+}
+
+// Wraps the error of the request in a ProbeError categorized by the step it failed at.
+func (t *probeTrace) wrap(err error) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	kind := Classify(err)
+	switch {
+	case kind == Canceled:
+	case t.dnsErr != nil:
+		kind = DNSFailure
+	case t.tlsErr != nil:
+		kind = TLSHandshakeFailure
+		if isCertificateError(t.tlsErr) {
+			kind = CertificateInvalid
+		}
+	case !t.connected:
+		if t.connectErr != nil {
+			kind = Classify(t.connectErr)
+		}
+		if kind == HTTPTimeout {
+			kind = ConnectTimeout
+		}
+	case kind == ConnectTimeout:
+		// A timeout once connected is a timeout of the request.
+		kind = HTTPTimeout
+	}
+
+	return &ProbeError{Kind: kind, Err: err}
+}
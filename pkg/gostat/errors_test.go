@@ -0,0 +1,64 @@
+package gostat
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// Testing the category of the failures of a probe.
+func TestProbeErrorKind(t *testing.T) {
+	plain := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/slow":
+			time.Sleep(500 * time.Millisecond)
+		case "/reset":
+			conn, _, _ := w.(http.Hijacker).Hijack()
+			conn.Close()
+		}
+	}))
+	defer plain.Close()
+	secure := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer secure.Close()
+
+	closed, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	closedPort := closed.Addr().(*net.TCPAddr).Port
+	closed.Close()
+
+	plainIP, plainPort := edgePort(t, plain)
+	secureIP, securePort := edgePort(t, secure)
+
+	for _, c := range []struct {
+		prober   *Prober
+		request  ProbeRequest
+		expected ErrorKind
+	}{
+		{&Prober{}, ProbeRequest{URL: "https://example.com/", EdgeIP: "127.0.0.1", Port: closedPort}, ConnectRefused},
+		{&Prober{Timeout: 100 * time.Millisecond}, ProbeRequest{URL: "http://example.com/slow", EdgeIP: plainIP, Port: plainPort}, HTTPTimeout},
+		{&Prober{}, ProbeRequest{URL: "http://example.com/reset", EdgeIP: plainIP, Port: plainPort}, ConnectionReset},
+		{&Prober{}, ProbeRequest{URL: "https://example.com/", EdgeIP: plainIP, Port: plainPort}, TLSHandshakeFailure},
+		{&Prober{}, ProbeRequest{URL: "https://example.com/", EdgeIP: secureIP, Port: securePort, TLS: TLSOptions{Verify: true}}, CertificateInvalid},
+	} {
+		_, err := c.prober.Probe(context.Background(), c.request)
+		if kind := KindOf(err); kind != c.expected {
+			t.Errorf("%s on port %d: expected %s, got %s (%v)", c.request.URL, c.request.Port, c.expected, kind, err)
+		}
+	}
+
+	if kind := Classify(fmt.Errorf("resolve: %w", &net.DNSError{Err: "no such host", Name: "example.invalid"})); kind != DNSFailure {
+		t.Errorf("expected %s, got %s", DNSFailure, kind)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := (&Prober{}).Probe(ctx, ProbeRequest{URL: "http://example.com/", EdgeIP: plainIP, Port: plainPort}); KindOf(err) != Canceled {
+		t.Errorf("expected %s, got %v", Canceled, err)
+	}
+}
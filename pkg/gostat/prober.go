@@ -0,0 +1,289 @@
+// Package gostat requests a url on a single edge of a CDN, whatever the DNS answer for
+// the domain is, and returns the response with its hash, latency and TLS information.
+// It is the library behind the gostat command and never prints or exits.
+package gostat
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/tcnksm/go-httpstat"
+)
+
+// TLS options of a probe. The handshake always succeeds so that the certificate of a
+// misconfigured edge can still be inspected, the verification result is in TLSInfo.VerifyError.
+type TLSOptions struct {
+	// Server name sent in the handshake and verified, the host of the url when empty.
+	ServerName string
+	// Versions offered to the edge, TLS 1.1 to TLS 1.3 when zero.
+	MinVersion uint16
+	MaxVersion uint16
+	// Roots the certificate is verified against, the system roots when nil.
+	RootCAs *x509.CertPool
+	// Fails the probe with a CertificateInvalid error when the certificate does not verify.
+	Verify bool
+	// Cache of the TLS sessions, shared between probes to resume a session from another one.
+	// A session only caches the sessions of its own connection when nil.
+	SessionCache tls.ClientSessionCache
+}
+
+// Request of a probe.
+type ProbeRequest struct {
+	// The http or https url to request.
+	URL string
+	// The edge the request is sent to instead of the DNS answer for the host of the url.
+	EdgeIP string
+	// Port of the edge, 80 for http and 443 for https when zero.
+	Port int
+	// GET when empty.
+	Method string
+	// Headers of the request, a Host header replaces the host of the request.
+	Header http.Header
+	// Number of bytes of the body kept in the result, the hash always covers the whole body.
+	BodyLimit int64
+	// Asks for the body as is instead of letting the transport negotiate gzip.
+	DisableCompression bool
+	TLS                TLSOptions
+}
+
+// Result of a probe.
+type ProbeResult struct {
+	URL           string
+	EdgeIP        string
+	Protocol      string
+	StatusCode    int
+	Status        string
+	Header        http.Header
+	RequestHeader http.Header
+	// SHA-256 of the body.
+	Hash     []byte
+	BodySize int64
+	// Up to BodyLimit bytes of the body.
+	Body    []byte
+	Timings httpstat.Result
+	TLS     *TLSInfo
+	// Whether the request was sent over a connection kept alive from a previous request.
+	Reused bool
+	// Whether the edge asked to close the connection after the response.
+	Closed bool
+}
+
+// Prober sends probes. The zero value is ready to use.
+type Prober struct {
+	// Timeout of establishing the connection, 5 seconds when zero.
+	DialTimeout time.Duration
+	// Timeout of the TLS handshake, 5 seconds when zero.
+	TLSHandshakeTimeout time.Duration
+	// Timeout of the whole probe including reading the body, no limit other than the context when zero.
+	Timeout time.Duration
+}
+
+func orDefault(d, fallback time.Duration) time.Duration {
+	if d == 0 {
+		return fallback
+	}
+	return d
+}
+
+// Sends the request to the edge and reads the whole response, until the context is done.
+// The result holds the url, edge and protocol even when an error is returned, errors of the
+// request are a *ProbeError with the category of the failure.
+func (p *Prober) Probe(ctx context.Context, request ProbeRequest) (ProbeResult, error) {
+	session, err := p.NewSession(request)
+	if err != nil {
+		return ProbeResult{URL: request.URL, EdgeIP: request.EdgeIP}, err
+	}
+	defer session.Close()
+
+	return session.Probe(ctx)
+}
+
+// Session sends the same request to an edge repeatedly over a single connection, kept alive
+// between requests as long as the edge allows it, and resumes the TLS session when the
+// edge closed the connection.
+type Session struct {
+	prober  *Prober
+	request ProbeRequest
+	target  *url.URL
+	client  *http.Client
+}
+
+// Returns a session of the request, which is not sent until Probe is called.
+func (p *Prober) NewSession(request ProbeRequest) (*Session, error) {
+	target, err := url.Parse(request.URL)
+	if err != nil {
+		return nil, &ProbeError{Kind: OtherError, Err: err}
+	}
+
+	transport, err := p.transport(target, request)
+	if err != nil {
+		return nil, &ProbeError{Kind: OtherError, Err: err}
+	}
+	transport.MaxConnsPerHost = 1
+	transport.MaxIdleConnsPerHost = 1
+	if transport.TLSClientConfig != nil && transport.TLSClientConfig.ClientSessionCache == nil {
+		transport.TLSClientConfig.ClientSessionCache = tls.NewLRUClientSessionCache(1)
+	}
+
+	return &Session{prober: p, request: request, target: target, client: &http.Client{Transport: transport}}, nil
+}
+
+// Closes the connection of the session.
+func (s *Session) Close() {
+	s.client.CloseIdleConnections()
+}
+
+// Sends the request of the session and reads the whole response, like Prober.Probe.
+func (s *Session) Probe(ctx context.Context) (ProbeResult, error) {
+	p, request, target, client := s.prober, s.request, s.target, s.client
+	result := ProbeResult{URL: request.URL, EdgeIP: request.EdgeIP, Protocol: target.Scheme}
+
+	if p.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.Timeout)
+		defer cancel()
+	}
+
+	method := request.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+
+	trace := &probeTrace{}
+	ctx = httpstat.WithHTTPStat(ctx, &result.Timings)
+	ctx = httptrace.WithClientTrace(ctx, trace.clientTrace())
+	req, err := http.NewRequestWithContext(ctx, method, request.URL, nil)
+	if err != nil {
+		return result, &ProbeError{Kind: OtherError, Err: err}
+	}
+	for field, values := range request.Header {
+		for _, value := range values {
+			req.Header.Add(field, value)
+		}
+	}
+	// net/http ignores the Host header field, so it is also set on the request itself.
+	if host := req.Header.Get("Host"); host != "" {
+		req.Host = host
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return result, trace.wrap(err)
+	}
+	defer resp.Body.Close()
+
+	hasher := sha256.New()
+	body := &limitedBuffer{limit: request.BodyLimit}
+	size, err := io.Copy(io.MultiWriter(hasher, body), resp.Body)
+	if err != nil {
+		return result, trace.wrap(err)
+	}
+	result.Timings.End(time.Now())
+
+	serverName := request.TLS.ServerName
+	if serverName == "" {
+		serverName = target.Hostname()
+	}
+
+	result.StatusCode = resp.StatusCode
+	result.Status = resp.Status
+	result.Header = resp.Header
+	result.RequestHeader = req.Header
+	result.Hash = hasher.Sum(nil)
+	result.BodySize = size
+	result.Body = body.data
+	result.TLS = newTLSInfo(resp.TLS, serverName, request.TLS.RootCAs)
+	result.Reused = trace.reused
+	result.Closed = resp.Close
+	if request.TLS.Verify && result.TLS != nil && result.TLS.VerifyError != "" {
+		return result, &ProbeError{Kind: CertificateInvalid, Err: errors.New(result.TLS.VerifyError)}
+	}
+	return result, nil
+}
+
+// Returns the transport pinned to the edge. Over http the edge is used as a proxy so that
+// the request line carries the url, over https every connection is dialed to the edge.
+func (p *Prober) transport(target *url.URL, request ProbeRequest) (*http.Transport, error) {
+	dialer := &net.Dialer{Timeout: orDefault(p.DialTimeout, 5*time.Second)}
+	transport := &http.Transport{
+		DialContext:         dialer.DialContext,
+		TLSHandshakeTimeout: orDefault(p.TLSHandshakeTimeout, 5*time.Second),
+		DisableCompression:  request.DisableCompression,
+	}
+
+	switch target.Scheme {
+	case "http":
+		if request.EdgeIP == "" {
+			return transport, nil
+		}
+		port := request.Port
+		if port == 0 {
+			port = 80
+		}
+		proxy, err := url.Parse(fmt.Sprintf("http://%s:%d@%s", target.Hostname(), port, net.JoinHostPort(request.EdgeIP, strconv.Itoa(port))))
+		if err != nil {
+			return nil, err
+		}
+		transport.Proxy = http.ProxyURL(proxy)
+
+	case "https":
+		minVersion, maxVersion := request.TLS.MinVersion, request.TLS.MaxVersion
+		if minVersion == 0 {
+			minVersion = tls.VersionTLS11
+		}
+		if maxVersion == 0 {
+			maxVersion = tls.VersionTLS13
+		}
+		transport.TLSClientConfig = &tls.Config{
+			ServerName:         request.TLS.ServerName,
+			InsecureSkipVerify: true,
+			MinVersion:         minVersion,
+			MaxVersion:         maxVersion,
+			ClientSessionCache: request.TLS.SessionCache,
+		}
+
+		if request.EdgeIP != "" {
+			port := request.Port
+			if port == 0 {
+				port = 443
+			}
+			edge := net.JoinHostPort(request.EdgeIP, strconv.Itoa(port))
+			transport.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+				return dialer.DialContext(ctx, network, edge)
+			}
+		}
+
+	default:
+		return nil, fmt.Errorf("unsupported protocol %q", target.Scheme)
+	}
+
+	return transport, nil
+}
+
+// A writer that keeps only the first limit bytes written to it.
+type limitedBuffer struct {
+	data  []byte
+	limit int64
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if remain := b.limit - int64(len(b.data)); remain > 0 {
+		if int64(len(p)) > remain {
+			b.data = append(b.data, p[:remain]...)
+		} else {
+			b.data = append(b.data, p...)
+		}
+	}
+	return len(p), nil
+}
@@ -0,0 +1,176 @@
+package gostat
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func edgePort(t *testing.T, srv *httptest.Server) (string, int) {
+	u, err := url.Parse(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var port int
+	fmt.Sscan(u.Port(), &port)
+	return u.Hostname(), port
+}
+
+// Testing that https requests are pinned to the edge whatever the host of the url is.
+func TestProbeHTTPS(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Host", r.Host)
+		fmt.Fprint(w, "hello")
+	}))
+	defer srv.Close()
+
+	ip, port := edgePort(t, srv)
+	roots := x509.NewCertPool()
+	roots.AddCert(srv.Certificate())
+
+	result, err := (&Prober{}).Probe(context.Background(), ProbeRequest{
+		URL:       "https://example.com/index.html",
+		EdgeIP:    ip,
+		Port:      port,
+		BodyLimit: 2,
+		TLS:       TLSOptions{RootCAs: roots},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sum := sha256.Sum256([]byte("hello"))
+	switch {
+	case result.StatusCode != 200 || result.Protocol != "https":
+		t.Errorf("unexpected status %d over %s", result.StatusCode, result.Protocol)
+	case result.Header.Get("X-Host") != "example.com":
+		t.Errorf("expected the host of the url, got %s", result.Header.Get("X-Host"))
+	case string(result.Hash) != string(sum[:]) || result.BodySize != 5 || string(result.Body) != "he":
+		t.Errorf("unexpected body %q of %d bytes", result.Body, result.BodySize)
+	case result.TLS == nil || result.TLS.VerifyError != "":
+		t.Errorf("expected a verified certificate, got %+v", result.TLS)
+	}
+
+	result, err = (&Prober{}).Probe(context.Background(), ProbeRequest{URL: "https://example.com/", EdgeIP: ip, Port: port})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.TLS == nil || result.TLS.VerifyError == "" {
+		t.Errorf("expected the certificate not to be trusted by the system roots")
+	}
+}
+
+// Testing that http requests are proxied through the edge with the Host header applied.
+func TestProbeHTTP(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Request", r.Method+" "+r.Host+" "+r.RequestURI)
+	}))
+	defer srv.Close()
+
+	ip, port := edgePort(t, srv)
+	result, err := (&Prober{}).Probe(context.Background(), ProbeRequest{
+		URL:    "http://example.com/index.html",
+		EdgeIP: ip,
+		Port:   port,
+		Method: http.MethodHead,
+		Header: http.Header{"Host": {"cdn.example.com"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := "HEAD cdn.example.com http://cdn.example.com/index.html"; result.Header.Get("X-Request") != expected {
+		t.Errorf("expected %q, got %q", expected, result.Header.Get("X-Request"))
+	}
+
+	if _, err := (&Prober{}).Probe(context.Background(), ProbeRequest{URL: "ftp://example.com/"}); err == nil {
+		t.Error("expected an unsupported protocol to be rejected")
+	}
+}
+
+// Testing that a session keeps the connection alive and resumes the TLS session once closed.
+func TestSession(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/close" {
+			w.Header().Set("Connection", "close")
+		}
+		fmt.Fprint(w, "hello")
+	}))
+	defer srv.Close()
+	ip, port := edgePort(t, srv)
+
+	for _, c := range []struct {
+		path    string
+		reused  bool
+		resumed bool
+	}{
+		{"/", true, false},
+		{"/close", false, true},
+	} {
+		session, err := (&Prober{}).NewSession(ProbeRequest{URL: "https://example.com" + c.path, EdgeIP: ip, Port: port})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for i := 0; i < 3; i++ {
+			result, err := session.Probe(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if i == 0 {
+				if result.Reused || result.TLS.Resumed {
+					t.Errorf("%s: expected a new connection first, got %+v", c.path, result)
+				}
+				continue
+			}
+			if result.Reused != c.reused || result.TLS.Resumed != c.resumed || result.Closed == c.reused {
+				t.Errorf("%s: request %d reused %t, resumed %t, closed %t", c.path, i, result.Reused, result.TLS.Resumed, result.Closed)
+			}
+		}
+		session.Close()
+	}
+}
+
+// Testing that a shared session cache resumes a session on another edge only when the
+// edges share their session ticket keys.
+func TestSessionCache(t *testing.T) {
+	key := [32]byte{1}
+	newEdge := func(shared bool) *httptest.Server {
+		srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		srv.StartTLS()
+		if shared {
+			srv.TLS.SetSessionTicketKeys([][32]byte{key})
+		}
+		return srv
+	}
+	a, b, other := newEdge(true), newEdge(true), newEdge(false)
+	defer a.Close()
+	defer b.Close()
+	defer other.Close()
+
+	probe := func(srv *httptest.Server, cache tls.ClientSessionCache) bool {
+		ip, port := edgePort(t, srv)
+		result, err := (&Prober{}).Probe(context.Background(), ProbeRequest{URL: "https://example.com/", EdgeIP: ip, Port: port, TLS: TLSOptions{SessionCache: cache}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return result.TLS.Resumed
+	}
+
+	cache := tls.NewLRUClientSessionCache(1)
+	if probe(a, cache) || !probe(a, cache) {
+		t.Error("expected a full handshake, then a resumed one on the same edge")
+	}
+	if !probe(b, cache) {
+		t.Error("expected the session to resume on an edge with the same ticket keys")
+	}
+	if probe(other, cache) {
+		t.Error("expected a full handshake on an edge with other ticket keys")
+	}
+}